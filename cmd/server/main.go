@@ -1,39 +1,67 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"giftredeem/internal/api"
-	"giftredeem/internal/db"
-	"log"
+	"giftredeem/internal/logging"
+	"giftredeem/pkg/server"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
 	// Load environment variables from .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found")
+	envErr := godotenv.Load()
+
+	// Defaults, then the config file, then environment variables
+	cfg, err := server.LoadConfig(*configFile)
+	if cfg == nil {
+		fatal("Failed to load configuration", err)
 	}
+	// 配置无效时日志配置也可能无效，此时保留默认的 text/info
+	_ = logging.Setup(cfg.Logging.Logging(), os.Stderr)
 
-	// Initialize database connection
-	if err := db.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	if *printConfig {
+		if err := yaml.NewEncoder(os.Stdout).Encode(cfg.Redacted()); err != nil {
+			fatal("Failed to print configuration", err)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if envErr != nil {
+		slog.Warn("No .env file found")
 	}
 
-	// Set up the API router
-	router := api.SetupRouter()
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-signals.Done()
+		stopSignals() // 再次收到信号时直接退出
+	}()
 
-	// Get the port from environment variable or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default port
+	if err := server.Run(signals, cfg); err != nil {
+		fatal("Server failed", err)
 	}
+}
 
-	// Start the server
-	serverAddr := fmt.Sprintf(":%s", port)
-	log.Printf("Server starting on %s\n", serverAddr)
-	if err := router.Run(serverAddr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
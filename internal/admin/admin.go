@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/internal/outbox"
+	"giftredeem/pkg/events"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidInput indicates invalid input data
+	ErrInvalidInput = errors.New("invalid input data")
+
+	// ErrUserNotFound indicates the target user does not exist
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// AdminService handles operations reserved for administrators
+type AdminService struct{}
+
+// NewAdminService creates a new admin service
+func NewAdminService() *AdminService {
+	return &AdminService{}
+}
+
+// UpdateUserStatus activates, bans or deletes a user account
+func (s *AdminService) UpdateUserStatus(ctx context.Context, adminID, userID uint, status, reason string) (*models.User, error) {
+	if status != "active" && status != "banned" && status != "deleted" {
+		return nil, ErrInvalidInput
+	}
+
+	var user models.User
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		previous := user.Status
+		if err := tx.Model(&user).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		err := audit.Record(ctx, tx, audit.Event{
+			ActorID:    &adminID,
+			Action:     audit.ActionAdminUserStatus,
+			TargetType: audit.TargetUser,
+			TargetID:   strconv.FormatUint(uint64(userID), 10),
+			Before:     map[string]interface{}{"status": previous},
+			After:      map[string]interface{}{"status": status, "reason": reason},
+		})
+		if err != nil || previous == status {
+			return err
+		}
+
+		return outbox.Publish(tx, events.UserStatusChanged, events.UserEvent{
+			UserID:         userID,
+			Status:         status,
+			PreviousStatus: previous,
+			Reason:         reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"giftredeem/internal/admin"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/models"
+	"giftredeem/internal/response"
+	"giftredeem/pkg/apitypes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles administrator requests
+type AdminHandler struct {
+	adminService *admin.AdminService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{
+		adminService: admin.NewAdminService(),
+	}
+}
+
+// GetAuditEvents queries the global audit log
+func (h *AdminHandler) GetAuditEvents(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid query: "+err.Error()))
+		return
+	}
+
+	// 管理员可以额外按操作者和福利筛选
+	if actorID := c.Query("actor_id"); actorID != "" {
+		id, err := strconv.ParseUint(actorID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid actor_id"))
+			return
+		}
+		uid := uint(id)
+		query.ActorID = &uid
+	}
+	if benefitID := c.Query("benefit_id"); benefitID != "" {
+		id, err := strconv.ParseUint(benefitID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid benefit_id"))
+			return
+		}
+		bid := uint(id)
+		query.BenefitID = &bid
+	}
+
+	events, total, err := audit.List(query)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve audit events: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.AuditPage{
+		Events: auditEventsData(events),
+		Total:  total,
+	}))
+}
+
+// UpdateUserStatus bans, restores or deletes a user account
+func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
+	// Get admin from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	adminUser := userValue.(*models.User)
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	// Parse request body
+	var input apitypes.UpdateUserStatusRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	user, err := h.adminService.UpdateUserStatus(c.Request.Context(), adminUser.ID, uint(userID), input.Status, input.Reason)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, admin.ErrUserNotFound) {
+			code = response.CodeNotFound
+		} else if errors.Is(err, admin.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to update user status: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.UserStatusResult{
+		User: apitypes.UserStatus{
+			ID:       user.ID,
+			Username: user.Username,
+			Status:   user.Status,
+		},
+	}))
+}
+
+// parseAuditQuery reads the common audit log filters from the query string
+func parseAuditQuery(c *gin.Context) (audit.Query, error) {
+	query := audit.Query{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return query, errors.New("since must be an RFC3339 timestamp")
+		}
+		query.Since = &t
+	}
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return query, errors.New("until must be an RFC3339 timestamp")
+		}
+		query.Until = &t
+	}
+
+	return query, nil
+}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"giftredeem/internal/auth"
 	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/metrics"
 	"giftredeem/internal/models"
 	"giftredeem/internal/response"
+	"giftredeem/internal/site"
+	"giftredeem/internal/tracing"
+	"giftredeem/pkg/apitypes"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
@@ -31,6 +35,8 @@ func NewAuthHandler() *AuthHandler {
 	}
 }
 
+var logger = logging.For("api")
+
 // GetProviders returns all enabled OAuth providers
 func (h *AuthHandler) GetProviders(c *gin.Context) {
 	providers, err := auth.GetEnabledProviders()
@@ -40,16 +46,16 @@ func (h *AuthHandler) GetProviders(c *gin.Context) {
 	}
 
 	// Map to simple response format
-	responseData := make([]map[string]interface{}, len(providers))
+	responseData := make([]apitypes.Provider, len(providers))
 	for i, p := range providers {
-		responseData[i] = map[string]interface{}{
-			"name":         p.Name,
-			"display_name": p.DisplayName,
+		responseData[i] = apitypes.Provider{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
 		}
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"providers": responseData,
+	c.JSON(http.StatusOK, response.Success(apitypes.ProviderList{
+		Providers: responseData,
 	}))
 }
 
@@ -73,8 +79,8 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"auth_url": authURL,
+	c.JSON(http.StatusOK, response.Success(apitypes.AuthURL{
+		AuthURL: authURL,
 	}))
 }
 
@@ -86,22 +92,16 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 		return
 	}
 
-	// 获取前端应用根URL，默认为本地开发环境
-	frontendBaseURL := c.Query("redirect_uri")
-	if frontendBaseURL == "" {
-		// 根据环境变量或配置获取前端URL
-		frontendBaseURL = os.Getenv("FRONTEND_URL")
-		if frontendBaseURL == "" {
-			frontendBaseURL = "http://localhost:3000" // 前端服务器端口
-		}
+	// 获取前端应用根URL；只接受本站域名，避免被用作开放重定向
+	siteConfig := site.Current()
+	frontendBaseURL := strings.TrimRight(c.Query("redirect_uri"), "/")
+	if frontendBaseURL == "" || !siteConfig.IsAllowedURL(frontendBaseURL) {
+		frontendBaseURL = siteConfig.FrontendBase(c.Request)
 	}
 
-	// 前端回调路径
-	callbackPath := "/auth/callback/" + providerName
-	errorPath := "/login" // 登录失败默认路径
-
 	// 处理OAuth回调
 	user, token, err := h.oauthHandler.HandleCallback(c, providerName)
+	observeLogin(providerName, err)
 
 	// 处理API调用模式 - 返回JSON而不是重定向
 	if c.GetHeader("Accept") == "application/json" || c.Query("response_type") == "json" {
@@ -114,14 +114,7 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 			}
 			c.JSON(http.StatusOK, response.Error(code, "Authentication failed: "+err.Error()))
 		} else {
-			c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-				"token": token,
-				"user": map[string]interface{}{
-					"id":         user.ID,
-					"username":   user.Username,
-					"avatar_url": user.AvatarURL,
-				},
-			}))
+			c.JSON(http.StatusOK, response.Success(loginResult(user, token)))
 		}
 		return
 	}
@@ -138,12 +131,11 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 			code = response.CodeAuthUserBanned
 		}
 		errorMsg := url.QueryEscape(fmt.Sprintf("%d:%s", code, err.Error()))
-		redirectURL = fmt.Sprintf("%s%s?error=%s", frontendBaseURL, errorPath, errorMsg)
+		redirectURL = fmt.Sprintf("%s?error=%s", siteConfig.FrontendLoginURL(frontendBaseURL), errorMsg)
 	} else {
 		// 登录成功，重定向到成功页面，带上token和用户信息
-		redirectURL = fmt.Sprintf("%s%s?token=%s&user_id=%d&username=%s&success=true",
-			frontendBaseURL,
-			callbackPath,
+		redirectURL = fmt.Sprintf("%s?token=%s&user_id=%d&username=%s&success=true",
+			siteConfig.FrontendCallbackURL(frontendBaseURL, providerName),
 			url.QueryEscape(token),
 			user.ID,
 			url.QueryEscape(user.Username))
@@ -154,8 +146,7 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 		}
 	}
 
-	// 调试信息
-	fmt.Printf("Redirecting to: %s\n", redirectURL)
+	logger.DebugContext(c.Request.Context(), "redirecting after OAuth callback", "provider", providerName, "redirect", logging.RedactURL(redirectURL))
 
 	// 执行重定向
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
@@ -180,25 +171,25 @@ func (h *AuthHandler) GetUserProfile(c *gin.Context) {
 	}
 
 	// Format OAuth accounts for response
-	accountsResponse := make([]map[string]interface{}, len(accounts))
+	accountsResponse := make([]apitypes.Account, len(accounts))
 	for i, acc := range accounts {
-		accountsResponse[i] = map[string]interface{}{
-			"provider":          acc.Provider,
-			"provider_username": acc.ProviderUsername,
-			"provider_email":    acc.ProviderEmail,
-			"provider_avatar":   acc.ProviderAvatar,
-			"created_at":        acc.CreatedAt,
-			"last_used_at":      acc.LastUsedAt,
+		accountsResponse[i] = apitypes.Account{
+			Provider:         acc.Provider,
+			ProviderUsername: acc.ProviderUsername,
+			ProviderEmail:    acc.ProviderEmail,
+			ProviderAvatar:   acc.ProviderAvatar,
+			CreatedAt:        acc.CreatedAt,
+			LastUsedAt:       acc.LastUsedAt,
 		}
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"username":   user.Username,
-			"avatar_url": user.AvatarURL,
-			"created_at": user.CreatedAt,
-			"accounts":   accountsResponse,
+	c.JSON(http.StatusOK, response.Success(apitypes.ProfileResult{
+		User: apitypes.Profile{
+			ID:        user.ID,
+			Username:  user.Username,
+			AvatarURL: user.AvatarURL,
+			CreatedAt: user.CreatedAt,
+			Accounts:  accountsResponse,
 		},
 	}))
 }
@@ -212,9 +203,7 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	}
 
 	// 从请求体中获取授权码
-	var request struct {
-		Code string `json:"code" binding:"required"`
-	}
+	var request apitypes.VerifyCodeRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid request: "+err.Error()))
@@ -223,26 +212,22 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 
 	// 获取提供商配置
 	var provider models.OAuthProvider
-	if err := db.DB.Where("name = ? AND enabled = ?", providerName, true).First(&provider).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("name = ? AND enabled = ?", providerName, true).First(&provider).Error; err != nil {
 		code := response.CodeServerError
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			code = response.CodeAuthProviderNotFound
 		}
+		observeLogin(providerName, auth.ErrInvalidProvider)
 		c.JSON(http.StatusOK, response.Error(code, "Provider not found or disabled"))
 		return
 	}
 
-	// 构建回调URL（用于交换令牌）
-	redirectURI := fmt.Sprintf("%s://%s/auth/callback/%s",
-		c.Request.URL.Scheme,
-		c.Request.Host,
-		providerName)
+	// 任何一步失败都记为登录失败
+	loginErr := auth.ErrFailedAuthentication
+	defer func() { observeLogin(providerName, loginErr) }()
 
-	if c.Request.URL.Scheme == "" {
-		redirectURI = fmt.Sprintf("http://%s/auth/callback/%s",
-			c.Request.Host,
-			providerName)
-	}
+	// 回调URL必须与发起授权时使用的完全一致
+	redirectURI := site.Current().OAuthCallbackURL(c.Request, providerName)
 
 	// 准备请求数据
 	data := url.Values{}
@@ -252,12 +237,17 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	data.Set("client_id", provider.ClientID)
 
 	// 处理 ClientSecret 可能为空的情况
-	if provider.ClientSecret != nil {
-		data.Set("client_secret", *provider.ClientSecret)
+	clientSecret, err := auth.ClientSecret(provider)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to decrypt client secret: "+err.Error()))
+		return
+	}
+	if clientSecret != "" {
+		data.Set("client_secret", clientSecret)
 	}
 
 	// 请求访问令牌
-	req, err := http.NewRequest("POST", provider.TokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", provider.TokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to create token request: "+err.Error()))
 		return
@@ -267,8 +257,10 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	req.Header.Add("Accept", "application/json")
 
 	// 发送请求
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := tracing.NewHTTPClient(10 * time.Second)
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.ObserveTokenExchange(providerName, start, err == nil && resp.StatusCode == http.StatusOK)
 	if err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to exchange code for token: "+err.Error()))
 		return
@@ -302,7 +294,7 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	}
 
 	// 获取用户信息
-	userInfoReq, err := http.NewRequest("GET", provider.UserInfoURL, nil)
+	userInfoReq, err := http.NewRequestWithContext(c.Request.Context(), "GET", provider.UserInfoURL, nil)
 	if err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to create user info request: "+err.Error()))
 		return
@@ -349,7 +341,7 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	}
 
 	// 查找或创建用户
-	user, err := h.oauthHandler.FindOrCreateUserFromInfo(providerName, userInfo, tokenData)
+	user, err := h.oauthHandler.FindOrCreateUserFromInfo(c.Request.Context(), providerName, userInfo, tokenData)
 	if err != nil {
 		code := response.CodeServerError
 		if err == auth.ErrUserBanned {
@@ -367,12 +359,26 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 	}
 
 	// 返回JWT令牌和用户信息
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"username":   user.Username,
-			"avatar_url": user.AvatarURL,
+	loginErr = nil
+	c.JSON(http.StatusOK, response.Success(loginResult(user, token)))
+}
+
+// loginResult formats the token and user of a successful login
+func loginResult(user *models.User, token string) apitypes.LoginResult {
+	return apitypes.LoginResult{
+		Token: token,
+		User: apitypes.UserSummary{
+			ID:        user.ID,
+			Username:  user.Username,
+			AvatarURL: user.AvatarURL,
 		},
-	}))
+	}
+}
+
+// observeLogin records a login attempt; unknown providers share one label
+func observeLogin(providerName string, err error) {
+	if errors.Is(err, auth.ErrInvalidProvider) {
+		providerName = metrics.UnknownProvider
+	}
+	metrics.ObserveLogin(providerName, err == nil)
 }
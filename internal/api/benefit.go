@@ -2,12 +2,13 @@ package api
 
 import (
 	"errors"
-	"fmt"
 	benefitpkg "giftredeem/internal/benefit"
-	"giftredeem/internal/db"
 	"giftredeem/internal/models"
 	"giftredeem/internal/response"
+	"giftredeem/internal/site"
+	"giftredeem/pkg/apitypes"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -37,15 +38,24 @@ func (h *BenefitHandler) CreateBenefit(c *gin.Context) {
 	user := userValue.(*models.User)
 
 	// Parse request body
-	var input benefitpkg.CreateBenefitInput
+	var input apitypes.CreateBenefitRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
 		return
 	}
 
 	// Create benefit
-	newBenefit, err := h.benefitService.CreateBenefit(user.ID, input)
+	newBenefit, err := h.benefitService.CreateBenefit(c.Request.Context(), user.ID, createBenefitInput(input))
 	if err != nil {
+		// 返回格式错误的兑换码及其行号
+		var importErr *benefitpkg.ImportError
+		if errors.As(err, &importErr) {
+			c.JSON(http.StatusOK, response.ErrorWithData(response.CodeInvalidInput, "Failed to create benefit: "+err.Error(), apitypes.ImportErrorDetail{
+				InvalidCodes: lineErrorsData(importErr.Lines),
+			}))
+			return
+		}
+
 		code := response.CodeBenefitCreationFailed
 		if errors.Is(err, benefitpkg.ErrInvalidInput) {
 			code = response.CodeInvalidInput
@@ -55,29 +65,14 @@ func (h *BenefitHandler) CreateBenefit(c *gin.Context) {
 		return
 	}
 
-	// Generate claim URL
-	baseURL := fmt.Sprintf("%s://%s", c.Request.URL.Scheme, c.Request.Host)
-	if c.Request.URL.Scheme == "" {
-		baseURL = "http://" + c.Request.Host // Default to HTTP if scheme is empty
-	}
+	// Claim links point at the frontend
+	baseURL := site.Current().FrontendBase(c.Request)
 
 	claimURL := h.benefitService.GetClaimURL(baseURL, newBenefit.UUID)
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"benefit": map[string]interface{}{
-			"id":                newBenefit.ID,
-			"uuid":              newBenefit.UUID,
-			"title":             newBenefit.Title,
-			"description":       newBenefit.Description,
-			"total_count":       newBenefit.TotalCount,
-			"claimed_count":     newBenefit.ClaimedCount,
-			"created_at":        newBenefit.CreatedAt,
-			"expires_at":        newBenefit.ExpiresAt,
-			"status":            newBenefit.Status,
-			"min_account_age":   newBenefit.MinAccountAge,
-			"allowed_providers": newBenefit.AllowedProviders,
-		},
-		"claim_url": claimURL,
+	c.JSON(http.StatusOK, response.Success(apitypes.CreateBenefitResult{
+		Benefit:  benefitData(*newBenefit, claimURL),
+		ClaimURL: claimURL,
 	}))
 }
 
@@ -99,33 +94,17 @@ func (h *BenefitHandler) GetUserBenefits(c *gin.Context) {
 		return
 	}
 
-	// Generate base URL for claim links
-	baseURL := fmt.Sprintf("%s://%s", c.Request.URL.Scheme, c.Request.Host)
-	if c.Request.URL.Scheme == "" {
-		baseURL = "http://" + c.Request.Host // Default to HTTP if scheme is empty
-	}
+	// Claim links point at the frontend
+	baseURL := site.Current().FrontendBase(c.Request)
 
 	// Format response
-	responseData := make([]map[string]interface{}, len(benefits))
+	responseData := make([]apitypes.Benefit, len(benefits))
 	for i, b := range benefits {
-		responseData[i] = map[string]interface{}{
-			"id":                b.ID,
-			"uuid":              b.UUID,
-			"title":             b.Title,
-			"description":       b.Description,
-			"total_count":       b.TotalCount,
-			"claimed_count":     b.ClaimedCount,
-			"created_at":        b.CreatedAt,
-			"expires_at":        b.ExpiresAt,
-			"status":            b.Status,
-			"claim_url":         h.benefitService.GetClaimURL(baseURL, b.UUID),
-			"allowed_providers": b.AllowedProviders,
-			"min_account_age":   b.MinAccountAge,
-		}
+		responseData[i] = benefitData(b, h.benefitService.GetClaimURL(baseURL, b.UUID))
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"benefits": responseData,
+	c.JSON(http.StatusOK, response.Success(apitypes.BenefitList{
+		Benefits: responseData,
 	}))
 }
 
@@ -148,24 +127,13 @@ func (h *BenefitHandler) GetUserClaims(c *gin.Context) {
 	}
 
 	// Format response
-	responseData := make([]map[string]interface{}, len(claims))
+	responseData := make([]apitypes.Claim, len(claims))
 	for i, claim := range claims {
-		responseData[i] = map[string]interface{}{
-			"id":             claim.ID,
-			"claimed_at":     claim.ClaimedAt,
-			"oauth_provider": claim.OAuthProvider,
-			"benefit": map[string]interface{}{
-				"id":          claim.Benefit.ID,
-				"uuid":        claim.Benefit.UUID,
-				"title":       claim.Benefit.Title,
-				"description": claim.Benefit.Description,
-			},
-			"code": claim.RedemptionCode.Code,
-		}
+		responseData[i] = claimData(claim, claim.Benefit)
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"claims": responseData,
+	c.JSON(http.StatusOK, response.Success(apitypes.ClaimList{
+		Claims: responseData,
 	}))
 }
 
@@ -188,9 +156,7 @@ func (h *BenefitHandler) UpdateBenefitStatus(c *gin.Context) {
 	}
 
 	// Parse request body
-	var input struct {
-		Status string `json:"status" binding:"required"`
-	}
+	var input apitypes.UpdateBenefitStatusRequest
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
@@ -198,7 +164,7 @@ func (h *BenefitHandler) UpdateBenefitStatus(c *gin.Context) {
 	}
 
 	// Update benefit status
-	err := h.benefitService.UpdateBenefitStatus(user.ID, benefitUUID, input.Status)
+	err := h.benefitService.UpdateBenefitStatus(c.Request.Context(), user.ID, benefitUUID, input.Status)
 	if err != nil {
 		code := response.CodeServerError
 		if errors.Is(err, benefitpkg.ErrNotFound) {
@@ -233,7 +199,7 @@ func (h *BenefitHandler) GetBenefitClaims(c *gin.Context) {
 	}
 
 	// Get claims
-	claims, err := h.benefitService.GetBenefitClaims(user.ID, benefitUUID)
+	claims, err := h.benefitService.GetBenefitClaims(c.Request.Context(), user.ID, benefitUUID)
 	if err != nil {
 		code := response.CodeServerError
 		if errors.Is(err, benefitpkg.ErrNotFound) {
@@ -245,22 +211,29 @@ func (h *BenefitHandler) GetBenefitClaims(c *gin.Context) {
 	}
 
 	// Format response
-	responseData := make([]map[string]interface{}, len(claims))
+	responseData := make([]apitypes.BenefitClaim, len(claims))
 	for i, claim := range claims {
-		responseData[i] = map[string]interface{}{
-			"id":             claim.ID,
-			"claimed_at":     claim.ClaimedAt,
-			"oauth_provider": claim.OAuthProvider,
-			"user": map[string]interface{}{
-				"id":       claim.User.ID,
-				"username": claim.User.Username,
-			},
-			"code": claim.RedemptionCode.Code,
-		}
+		responseData[i] = benefitClaimData(claim)
+	}
+
+	// Get tier distribution
+	tiers, err := h.benefitService.GetTierDistribution(user.ID, benefitUUID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve tier distribution: "+err.Error()))
+		return
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"claims": responseData,
+	// Get feedback summary
+	feedback, err := h.benefitService.GetFeedbackSummary(user.ID, benefitUUID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve feedback summary: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.BenefitClaims{
+		Claims:           responseData,
+		TierDistribution: tierStatsData(tiers),
+		FeedbackSummary:  (*apitypes.FeedbackSummary)(feedback),
 	}))
 }
 
@@ -295,12 +268,25 @@ func (h *BenefitHandler) GetBenefitByUUID(c *gin.Context) {
 
 	// Check if benefit is active
 	var claimStatus string = "available"
+	remainingQuota := benefit.PerUserQuota
+	var periodRemaining *int
+	var periodResetsAt *time.Time
 	if userID > 0 {
-		// Check if user has already claimed
-		var existingClaim models.Claim
-		err := db.DB.Where("user_id = ? AND benefit_id = ?", userID, benefit.ID).First(&existingClaim).Error
-		if err == nil {
+		// Check if user has used up the quota or the limit of the current period
+		quota, err := h.benefitService.GetRemainingQuota(userID, benefit)
+		if err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve claim status: "+err.Error()))
+			return
+		}
+		remainingQuota = quota.Remaining
+		if quota.PeriodResetsAt != nil {
+			periodRemaining = &quota.PeriodRemaining
+			periodResetsAt = quota.PeriodResetsAt
+		}
+		if remainingQuota == 0 {
 			claimStatus = "claimed"
+		} else if quota.PeriodRemaining == 0 {
+			claimStatus = "period_limit_reached"
 		}
 	}
 
@@ -321,23 +307,12 @@ func (h *BenefitHandler) GetBenefitByUUID(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"benefit": map[string]interface{}{
-			"uuid":          benefit.UUID,
-			"title":         benefit.Title,
-			"description":   benefit.Description,
-			"total_count":   benefit.TotalCount,
-			"claimed_count": benefit.ClaimedCount,
-			"created_at":    benefit.CreatedAt,
-			"expires_at":    benefit.ExpiresAt,
-			"creator": map[string]interface{}{
-				"username": benefit.Creator.Username,
-				"id":       benefit.CreatorID,
-			},
-			"allowed_providers": benefit.AllowedProviders,
-			"min_account_age":   benefit.MinAccountAge,
-		},
-		"claim_status": claimStatus,
+	c.JSON(http.StatusOK, response.Success(apitypes.BenefitInfo{
+		Benefit:         publicBenefitData(*benefit),
+		ClaimStatus:     claimStatus,
+		RemainingQuota:  remainingQuota,
+		PeriodRemaining: periodRemaining,
+		PeriodResetsAt:  periodResetsAt,
 	}))
 }
 
@@ -374,22 +349,22 @@ func (h *BenefitHandler) ClaimBenefit(c *gin.Context) {
 		}
 	}
 
-	// Get the benefit first to include in the response
-	benefit, err := h.benefitService.GetBenefitByUUID(benefitUUID)
-	if err != nil {
-		code := response.CodeServerError
-		if errors.Is(err, benefitpkg.ErrNotFound) {
-			code = response.CodeBenefitNotFound
+	// The challenge answer is optional; benefits without a challenge accept an empty body
+	var proof apitypes.ClaimRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&proof); err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+			return
 		}
-		c.JSON(http.StatusOK, response.Error(code, "Failed to retrieve benefit: "+err.Error()))
-		return
 	}
 
 	// Claim the benefit
-	redemptionCode, err := h.benefitService.ClaimBenefit(
+	claim, err := h.benefitService.ClaimBenefit(
+		c.Request.Context(),
 		user.ID,
 		benefitUUID,
 		provider,
+		benefitpkg.ClaimProof(proof),
 		c.ClientIP(),
 		c.Request.UserAgent(),
 	)
@@ -407,6 +382,16 @@ func (h *BenefitHandler) ClaimBenefit(c *gin.Context) {
 			code = response.CodeBenefitDepleted
 		} else if errors.Is(err, benefitpkg.ErrAlreadyClaimed) {
 			code = response.CodeBenefitAlreadyClaimed
+		} else if errors.Is(err, benefitpkg.ErrPeriodLimitReached) {
+			code = response.CodeBenefitPeriodLimit
+		} else if errors.Is(err, benefitpkg.ErrUserBannedByCreator) {
+			code = response.CodeBenefitUserBanned
+		} else if errors.Is(err, benefitpkg.ErrChallengeRequired) {
+			code = response.CodeChallengeRequired
+		} else if errors.Is(err, benefitpkg.ErrChallengeFailed) {
+			code = response.CodeChallengeFailed
+		} else if errors.Is(err, benefitpkg.ErrClaimBlocked) {
+			code = response.CodeClaimBlocked
 		} else if errors.Is(err, benefitpkg.ErrProviderNotAllowed) || errors.Is(err, benefitpkg.ErrAccountTooNew) {
 			code = response.CodeBenefitIneligible
 		}
@@ -415,17 +400,315 @@ func (h *BenefitHandler) ClaimBenefit(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, response.Success(map[string]interface{}{
-		"claim": map[string]interface{}{
-			"claimed_at":     time.Now(),
-			"oauth_provider": provider,
-			"benefit": map[string]interface{}{
-				"id":          benefit.ID,
-				"uuid":        benefit.UUID,
-				"title":       benefit.Title,
-				"description": benefit.Description,
-			},
-			"code": redemptionCode.Code,
+	// 使用领取事务提交后的福利，计数已包含本次领取
+	c.JSON(http.StatusOK, response.Success(apitypes.ClaimResult{
+		Claim:        claimData(*claim, claim.Benefit),
+		ClaimedCount: claim.Benefit.ClaimedCount,
+		TotalCount:   claim.Benefit.TotalCount,
+	}))
+}
+
+// GetChallenge issues the challenge to solve before claiming a benefit
+func (h *BenefitHandler) GetChallenge(c *gin.Context) {
+	benefitUUID := c.Param("uuid")
+	if benefitUUID == "" {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID is required"))
+		return
+	}
+
+	info, err := h.benefitService.IssueChallenge(benefitUUID)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		}
+		c.JSON(http.StatusOK, response.Error(code, "Failed to issue challenge: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(challengeData(info)))
+}
+
+// claimCodes lists the redemption codes carried by a claim
+func claimCodes(claim models.Claim) []string {
+	codes := make([]string, 0, len(claim.Items))
+	for _, item := range claim.Items {
+		// 已被替换的兑换码不再展示
+		if item.ReplacedAt == nil {
+			codes = append(codes, item.RedemptionCode.Code)
+		}
+	}
+
+	// 兼容没有 claim_items 的旧记录
+	if len(codes) == 0 && claim.RedemptionCode.Code != "" {
+		codes = append(codes, claim.RedemptionCode.Code)
+	}
+	return codes
+}
+
+// claimerView hides the codes of a claim held for the creator's review from the claimer
+func claimerView(claim models.Claim) models.Claim {
+	if claim.ReviewStatus == benefitpkg.ReviewPending {
+		claim.Items = nil
+		claim.RedemptionCode = models.RedemptionCode{}
+	}
+	return claim
+}
+
+// claimItems lists the codes of a claim together with their prize tier and metadata
+func claimItems(claim models.Claim) []apitypes.ClaimItem {
+	items := make([]apitypes.ClaimItem, 0, len(claim.Items))
+	for _, item := range claim.Items {
+		items = append(items, claimItemData(item))
+	}
+	return items
+}
+
+// SubmitFeedback lets a claimer report whether a claimed code worked
+func (h *BenefitHandler) SubmitFeedback(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	// Get claim ID from path
+	claimID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid claim ID"))
+		return
+	}
+
+	// Parse request body
+	var input apitypes.FeedbackRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	item, err := h.benefitService.SubmitFeedback(user.ID, uint(claimID), input.ItemID, input.Feedback, input.Note)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrClaimNotFound) {
+			code = response.CodeClaimNotFound
+		} else if errors.Is(err, benefitpkg.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		} else if errors.Is(err, benefitpkg.ErrReplacementNotAllowed) {
+			code = response.CodeReplacementNotAllowed
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to submit feedback: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.FeedbackResult{
+		Item: apitypes.FeedbackItem{
+			ID:           item.ID,
+			Feedback:     item.Feedback,
+			FeedbackNote: item.FeedbackNote,
+			FeedbackAt:   item.FeedbackAt,
 		},
 	}))
 }
+
+// ReplaceCode issues a replacement for a code reported as already used
+func (h *BenefitHandler) ReplaceCode(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	// Get benefit UUID and claim ID from path
+	benefitUUID := c.Param("uuid")
+	claimID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if benefitUUID == "" || err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID and claim ID are required"))
+		return
+	}
+
+	// Parse request body (optional for single-code claims)
+	var input apitypes.ReplaceCodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+			return
+		}
+	}
+
+	item, err := h.benefitService.ReplaceCode(c.Request.Context(), user.ID, benefitUUID, uint(claimID), input.ItemID)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		} else if errors.Is(err, benefitpkg.ErrClaimNotFound) {
+			code = response.CodeClaimNotFound
+		} else if errors.Is(err, benefitpkg.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		} else if errors.Is(err, benefitpkg.ErrReplacementNotAllowed) {
+			code = response.CodeReplacementNotAllowed
+		} else if errors.Is(err, benefitpkg.ErrNoCodeAvailable) {
+			code = response.CodeBenefitDepleted
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to replace code: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.ClaimItemResult{
+		Item: claimItemData(*item),
+	}))
+}
+
+// RevokeClaim voids a claim on one of the current user's benefits
+func (h *BenefitHandler) RevokeClaim(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	// Get benefit UUID and claim ID from path
+	benefitUUID := c.Param("uuid")
+	claimID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if benefitUUID == "" || err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID and claim ID are required"))
+		return
+	}
+
+	// Parse request body
+	var input apitypes.RevokeClaimRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	claim, err := h.benefitService.RevokeClaim(c.Request.Context(), user.ID, benefitUUID, uint(claimID), benefitpkg.RevokeClaimInput(input))
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		} else if errors.Is(err, benefitpkg.ErrClaimNotFound) {
+			code = response.CodeClaimNotFound
+		} else if errors.Is(err, benefitpkg.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to revoke claim: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.RevokeClaimResult{
+		Claim: apitypes.RevokedClaim{
+			ID:           claim.ID,
+			Status:       claim.Status,
+			RevokedAt:    claim.RevokedAt,
+			RevokeReason: claim.RevokeReason,
+		},
+	}))
+}
+
+// ReviewClaim approves or rejects a flagged or held claim on one of the current user's benefits
+func (h *BenefitHandler) ReviewClaim(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	// Get benefit UUID and claim ID from path
+	benefitUUID := c.Param("uuid")
+	claimID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if benefitUUID == "" || err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID and claim ID are required"))
+		return
+	}
+
+	// Parse request body
+	var input apitypes.ReviewClaimRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	claim, err := h.benefitService.ReviewClaim(c.Request.Context(), user.ID, benefitUUID, uint(claimID), benefitpkg.ReviewClaimInput(input))
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		} else if errors.Is(err, benefitpkg.ErrClaimNotFound) {
+			code = response.CodeClaimNotFound
+		} else if errors.Is(err, benefitpkg.ErrClaimNotUnderReview) {
+			code = response.CodeClaimNotUnderReview
+		} else if errors.Is(err, benefitpkg.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to review claim: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.ReviewClaimResult{
+		Claim: apitypes.ReviewedClaim{
+			ID:           claim.ID,
+			Status:       claim.Status,
+			ReviewStatus: claim.ReviewStatus,
+			ReviewedAt:   claim.ReviewedAt,
+			RevokeReason: claim.RevokeReason,
+		},
+	}))
+}
+
+// GetBenefitAudit retrieves the audit events of one of the current user's benefits
+func (h *BenefitHandler) GetBenefitAudit(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	// Get benefit UUID from path
+	benefitUUID := c.Param("uuid")
+	if benefitUUID == "" {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID is required"))
+		return
+	}
+
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid query: "+err.Error()))
+		return
+	}
+
+	events, total, err := h.benefitService.GetBenefitAuditEvents(user.ID, benefitUUID, query)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to retrieve audit events: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.AuditPage{
+		Events: auditEventsData(events),
+		Total:  total,
+	}))
+}
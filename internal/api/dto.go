@@ -0,0 +1,334 @@
+package api
+
+import (
+	benefitpkg "giftredeem/internal/benefit"
+	"giftredeem/internal/models"
+	"giftredeem/pkg/apitypes"
+)
+
+// Conversions between the models and the request and response bodies in pkg/apitypes.
+// Struct conversions such as apitypes.TierStat(stat) only compile while both types
+// have the same fields, so the two cannot drift apart unnoticed. Nil slices stay nil
+// and are encoded as null, as before.
+
+// benefitData formats a benefit for its creator
+func benefitData(b models.Benefit, claimURL string) apitypes.Benefit {
+	return apitypes.Benefit{
+		ID:                  b.ID,
+		UUID:                b.UUID,
+		Title:               b.Title,
+		Description:         b.Description,
+		TotalCount:          b.TotalCount,
+		ClaimedCount:        b.ClaimedCount,
+		CreatedAt:           b.CreatedAt,
+		ExpiresAt:           b.ExpiresAt,
+		Status:              b.Status,
+		ClaimURL:            claimURL,
+		AllowedProviders:    b.AllowedProviders,
+		MinAccountAge:       b.MinAccountAge,
+		PerUserQuota:        b.PerUserQuota,
+		CodesPerClaim:       b.CodesPerClaim,
+		PeriodLimit:         b.PeriodLimit,
+		LimitPeriod:         b.LimitPeriod,
+		AllocationMode:      b.AllocationMode,
+		TierRules:           tierRulesData(b.TierRules),
+		CodeFormat:          b.CodeFormat,
+		Challenge:           b.Challenge,
+		ChallengeDifficulty: b.ChallengeDifficulty,
+		FraudThreshold:      b.FraudThreshold,
+		FraudAction:         b.FraudAction,
+		Private:             b.Private,
+	}
+}
+
+// publicBenefitData formats a benefit for its claim page
+func publicBenefitData(b models.Benefit) apitypes.PublicBenefit {
+	return apitypes.PublicBenefit{
+		UUID:         b.UUID,
+		Title:        b.Title,
+		Description:  b.Description,
+		TotalCount:   b.TotalCount,
+		ClaimedCount: b.ClaimedCount,
+		CreatedAt:    b.CreatedAt,
+		ExpiresAt:    b.ExpiresAt,
+		Creator: apitypes.Creator{
+			ID:       b.CreatorID,
+			Username: b.Creator.Username,
+		},
+		AllowedProviders: b.AllowedProviders,
+		MinAccountAge:    b.MinAccountAge,
+		PerUserQuota:     b.PerUserQuota,
+		CodesPerClaim:    b.CodesPerClaim,
+		PeriodLimit:      b.PeriodLimit,
+		LimitPeriod:      b.LimitPeriod,
+		Challenge:        b.Challenge,
+	}
+}
+
+// benefitRef identifies the benefit of a claim
+func benefitRef(b models.Benefit) apitypes.BenefitRef {
+	return apitypes.BenefitRef{
+		ID:          b.ID,
+		UUID:        b.UUID,
+		Title:       b.Title,
+		Description: b.Description,
+	}
+}
+
+// claimData formats a claim for the claimer; the codes of a claim held for review are hidden
+func claimData(claim models.Claim, benefit models.Benefit) apitypes.Claim {
+	claim = claimerView(claim)
+	return apitypes.Claim{
+		ID:            claim.ID,
+		ClaimedAt:     claim.ClaimedAt,
+		OAuthProvider: claim.OAuthProvider,
+		ReviewStatus:  claim.ReviewStatus,
+		Benefit:       benefitRef(benefit),
+		Code:          claim.RedemptionCode.Code,
+		Codes:         claimCodes(claim),
+		Items:         claimItems(claim),
+	}
+}
+
+// benefitClaimData formats a claim for the creator of the benefit
+func benefitClaimData(claim models.Claim) apitypes.BenefitClaim {
+	var reasons []apitypes.RiskReason
+	if claim.RiskReasons != nil {
+		reasons = make([]apitypes.RiskReason, len(claim.RiskReasons))
+		for i, r := range claim.RiskReasons {
+			reasons[i] = apitypes.RiskReason(r)
+		}
+	}
+
+	return apitypes.BenefitClaim{
+		ID:            claim.ID,
+		ClaimedAt:     claim.ClaimedAt,
+		OAuthProvider: claim.OAuthProvider,
+		Status:        claim.Status,
+		RevokeReason:  claim.RevokeReason,
+		IPAddress:     claim.IPAddress,
+		RiskScore:     claim.RiskScore,
+		RiskReasons:   reasons,
+		ReviewStatus:  claim.ReviewStatus,
+		ReviewedAt:    claim.ReviewedAt,
+		User: apitypes.UserRef{
+			ID:       claim.User.ID,
+			Username: claim.User.Username,
+		},
+		Code:  claim.RedemptionCode.Code,
+		Codes: claimCodes(claim),
+		Items: claimItems(claim),
+	}
+}
+
+// claimItemData formats a single claimed code
+func claimItemData(item models.ClaimItem) apitypes.ClaimItem {
+	return apitypes.ClaimItem{
+		ID:           item.ID,
+		Code:         item.RedemptionCode.Code,
+		Tier:         item.RedemptionCode.Tier,
+		TierValue:    item.RedemptionCode.TierValue,
+		Metadata:     apitypes.CodeMetadata(item.RedemptionCode.Metadata),
+		Feedback:     item.Feedback,
+		FeedbackNote: item.FeedbackNote,
+		FeedbackAt:   item.FeedbackAt,
+		ReplacedByID: item.ReplacedByID,
+		ReplacedAt:   item.ReplacedAt,
+	}
+}
+
+// tierRulesData formats the tier rules of a benefit
+func tierRulesData(rules models.TierRules) []apitypes.TierRule {
+	if rules == nil {
+		return nil
+	}
+	data := make([]apitypes.TierRule, len(rules))
+	for i, r := range rules {
+		data[i] = apitypes.TierRule(r)
+	}
+	return data
+}
+
+// tierStatsData formats the tier distribution of a benefit
+func tierStatsData(stats []benefitpkg.TierStat) []apitypes.TierStat {
+	if stats == nil {
+		return nil
+	}
+	data := make([]apitypes.TierStat, len(stats))
+	for i, s := range stats {
+		data[i] = apitypes.TierStat(s)
+	}
+	return data
+}
+
+// challengeData formats the challenge to solve before claiming
+func challengeData(info *benefitpkg.ChallengeInfo) apitypes.Challenge {
+	data := apitypes.Challenge{Type: info.Type, SiteKey: info.SiteKey}
+	if info.PoW != nil {
+		pow := apitypes.PoW(*info.PoW)
+		data.PoW = &pow
+	}
+	return data
+}
+
+// auditEventsData formats audit events
+func auditEventsData(events []models.AuditEvent) []apitypes.AuditEvent {
+	if events == nil {
+		return nil
+	}
+	data := make([]apitypes.AuditEvent, len(events))
+	for i, e := range events {
+		data[i] = apitypes.AuditEvent{
+			ID:         e.ID,
+			ActorID:    e.ActorID,
+			Action:     e.Action,
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+			BenefitID:  e.BenefitID,
+			Before:     e.Before,
+			After:      e.After,
+			IPAddress:  e.IPAddress,
+			UserAgent:  e.UserAgent,
+			CreatedAt:  e.CreatedAt,
+		}
+	}
+	return data
+}
+
+// endpointData formats a webhook endpoint; the secret is only included when it was just created
+func endpointData(endpoint *models.WebhookEndpoint, withSecret bool) apitypes.WebhookEndpoint {
+	data := apitypes.WebhookEndpoint{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		Events:    endpoint.Events,
+		Enabled:   endpoint.Enabled,
+		CreatedAt: endpoint.CreatedAt,
+	}
+	if withSecret {
+		data.Secret = endpoint.Secret
+	}
+	return data
+}
+
+// deliveryData formats a webhook delivery
+func deliveryData(d models.WebhookDelivery) apitypes.WebhookDelivery {
+	return apitypes.WebhookDelivery{
+		ID:             d.ID,
+		UUID:           d.UUID,
+		EndpointID:     d.EndpointID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastError:      d.LastError,
+		ResponseStatus: d.ResponseStatus,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
+
+// deliveriesData formats the delivery log of a webhook endpoint
+func deliveriesData(deliveries []models.WebhookDelivery) []apitypes.WebhookDelivery {
+	if deliveries == nil {
+		return nil
+	}
+	data := make([]apitypes.WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		data[i] = deliveryData(d)
+	}
+	return data
+}
+
+// notificationsData formats inbox entries
+func notificationsData(notifications []models.Notification) []apitypes.Notification {
+	if notifications == nil {
+		return nil
+	}
+	data := make([]apitypes.Notification, len(notifications))
+	for i, n := range notifications {
+		data[i] = apitypes.Notification{
+			ID:        n.ID,
+			UserID:    n.UserID,
+			Kind:      n.Kind,
+			Title:     n.Title,
+			Body:      n.Body,
+			Link:      n.Link,
+			ReadAt:    n.ReadAt,
+			CreatedAt: n.CreatedAt,
+		}
+	}
+	return data
+}
+
+// preferencesData formats notification preferences, without the unsubscribe token
+func preferencesData(p *models.NotificationPreference) apitypes.NotificationPreferences {
+	return apitypes.NotificationPreferences{
+		UserID:          p.UserID,
+		Locale:          p.Locale,
+		Email:           p.Email,
+		ClaimReceipt:    p.ClaimReceipt,
+		BenefitDepleted: p.BenefitDepleted,
+		BenefitExpiring: p.BenefitExpiring,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+// lineErrorsData formats the malformed codes of a failed import
+func lineErrorsData(lines []benefitpkg.LineError) []apitypes.LineError {
+	data := make([]apitypes.LineError, len(lines))
+	for i, l := range lines {
+		data[i] = apitypes.LineError(l)
+	}
+	return data
+}
+
+// createBenefitInput converts a create request into the input of the benefit service
+func createBenefitInput(req apitypes.CreateBenefitRequest) benefitpkg.CreateBenefitInput {
+	input := benefitpkg.CreateBenefitInput{
+		Title:               req.Title,
+		Description:         req.Description,
+		Codes:               req.Codes,
+		ExpiresAt:           req.ExpiresAt,
+		AllowedProviders:    req.AllowedProviders,
+		MinAccountAge:       req.MinAccountAge,
+		ClaimConditions:     req.ClaimConditions,
+		PerUserQuota:        req.PerUserQuota,
+		CodesPerClaim:       req.CodesPerClaim,
+		PeriodLimit:         req.PeriodLimit,
+		LimitPeriod:         req.LimitPeriod,
+		AllocationMode:      req.AllocationMode,
+		CodeFormat:          req.CodeFormat,
+		CodePattern:         req.CodePattern,
+		CodeMetadata:        models.CodeMetadata(req.CodeMetadata),
+		Challenge:           req.Challenge,
+		ChallengeDifficulty: req.ChallengeDifficulty,
+		FraudThreshold:      req.FraudThreshold,
+		FraudAction:         req.FraudAction,
+		Private:             req.Private,
+	}
+
+	if req.Entries != nil {
+		input.Entries = make([]benefitpkg.CodeEntry, len(req.Entries))
+		for i, e := range req.Entries {
+			input.Entries[i] = benefitpkg.CodeEntry{Code: e.Code, Tier: e.Tier, Metadata: models.CodeMetadata(e.Metadata)}
+		}
+	}
+	if req.Tiers != nil {
+		input.Tiers = make([]benefitpkg.TierInput, len(req.Tiers))
+		for i, t := range req.Tiers {
+			input.Tiers[i] = benefitpkg.TierInput{Name: t.Name, Weight: t.Weight, Value: t.Value, Codes: t.Codes}
+			if t.Metadata != nil {
+				metadata := models.CodeMetadata(*t.Metadata)
+				input.Tiers[i].Metadata = &metadata
+			}
+		}
+	}
+	if req.TierRules != nil {
+		input.TierRules = make([]models.TierRule, len(req.TierRules))
+		for i, r := range req.TierRules {
+			input.TierRules[i] = models.TierRule(r)
+		}
+	}
+	return input
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"giftredeem/internal/auth"
+	"giftredeem/internal/db"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readyTimeout bounds the checks of a single readiness probe
+const readyTimeout = 3 * time.Second
+
+// HealthHandler serves the liveness and readiness probes. Unlike the rest of the API
+// the probes answer with HTTP status codes, which is what orchestrators look at.
+type HealthHandler struct {
+	requiredProviders []string
+}
+
+// NewHealthHandler creates a health handler. requiredProviders must be enabled for the
+// server to be ready.
+func NewHealthHandler(requiredProviders []string) *HealthHandler {
+	return &HealthHandler{requiredProviders: requiredProviders}
+}
+
+// Healthz reports that the process is alive
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the server can handle requests: the database is reachable,
+// migrations are applied and the required OAuth providers are enabled
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	checks := gin.H{}
+	ready := true
+	check := func(name string, fn func() error) {
+		// 数据库不可用时后续检查没有意义
+		if !ready {
+			checks[name] = "skipped"
+			return
+		}
+		if err := fn(); err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	check("database", func() error { return db.Ping(ctx) })
+	check("migrations", func() error { return db.CheckMigrations(ctx) })
+	check("oauth_providers", func() error { return auth.CheckProviders(ctx, h.requiredProviders) })
+
+	status, code := "ok", http.StatusOK
+	if !ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"errors"
+	"giftredeem/internal/models"
+	"giftredeem/internal/notify"
+	"giftredeem/internal/response"
+	"giftredeem/pkg/apitypes"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles in-app notification and notification preference requests
+type NotificationHandler struct {
+	inboxService      *notify.InboxService
+	preferenceService *notify.PreferenceService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler() *NotificationHandler {
+	return &NotificationHandler{
+		inboxService:      notify.NewInboxService(),
+		preferenceService: notify.NewPreferenceService(),
+	}
+}
+
+// GetNotifications lists the current user's in-app notifications
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	unreadOnly := c.Query("unread") == "true" || c.Query("unread") == "1"
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	notifications, total, err := h.inboxService.List(user.ID, unreadOnly, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve notifications: "+err.Error()))
+		return
+	}
+
+	unread, err := h.inboxService.UnreadCount(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve notifications: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.NotificationList{
+		Notifications: notificationsData(notifications),
+		Total:         total,
+		UnreadCount:   unread,
+	}))
+}
+
+// GetUnreadCount returns the number of unread notifications, cheap enough to poll for a bell icon
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	unread, err := h.inboxService.UnreadCount(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve unread count: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.UnreadCount{
+		UnreadCount: unread,
+	}))
+}
+
+// MarkRead marks notifications as read; an empty ID list marks all of them
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	var input apitypes.MarkReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+			return
+		}
+	}
+
+	updated, err := h.inboxService.MarkRead(user.ID, input.IDs)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to mark notifications as read: "+err.Error()))
+		return
+	}
+
+	unread, err := h.inboxService.UnreadCount(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve unread count: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.MarkReadResult{
+		Updated:     updated,
+		UnreadCount: unread,
+	}))
+}
+
+// GetPreferences returns the current user's notification preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	prefs, err := h.preferenceService.GetPreferences(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve preferences: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(preferencesData(prefs)))
+}
+
+// UpdatePreferences changes the current user's notification preferences
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	var input apitypes.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	prefs, err := h.preferenceService.UpdatePreferences(user.ID, notify.PreferenceInput(input))
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, notify.ErrInvalidInput) {
+			code = response.CodeInvalidInput
+		}
+		c.JSON(http.StatusOK, response.Error(code, "Failed to update preferences: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(preferencesData(prefs)))
+}
+
+// unsubscribePage is shown when an unsubscribe link is opened from an email
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>GiftRedeem</title></head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 80px; color: #222;">
+<p>{{.}}</p>
+</body>
+</html>`))
+
+// Unsubscribe turns off emails for the owner of the token. GET requests come from
+// a person clicking the link and get a page; POST requests are RFC 8058 one-click
+// unsubscribes sent by mail clients and get the standard JSON response.
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	kind := c.Query("kind")
+
+	prefs, err := h.preferenceService.Unsubscribe(token, kind)
+
+	if c.Request.Method == http.MethodPost {
+		if err != nil {
+			code := response.CodeServerError
+			if errors.Is(err, notify.ErrInvalidToken) || errors.Is(err, notify.ErrInvalidInput) {
+				code = response.CodeInvalidInput
+			}
+			c.JSON(http.StatusOK, response.Error(code, "Failed to unsubscribe: "+err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, response.Success(nil))
+		return
+	}
+
+	message := "退订成功，你将不再收到此类邮件。"
+	if err != nil {
+		message = "退订链接无效。 / This unsubscribe link is invalid."
+	} else if prefs.Locale == notify.LocaleEN {
+		message = "You have been unsubscribed and will no longer receive these emails."
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(c.Writer, message)
+}
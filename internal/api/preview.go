@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"giftredeem/internal/benefit"
+	"giftredeem/internal/frontend"
+	"giftredeem/internal/site"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siteName is shown by link previews next to the page title
+const siteName = "GiftRedeem"
+
+// maxPreviewDescription bounds the description in link previews, in characters
+const maxPreviewDescription = 160
+
+// PreviewHandler renders pages of the frontend with link preview tags, so that links
+// shared into chat apps show what they point to
+type PreviewHandler struct {
+	frontend       *frontend.Server
+	benefitService *benefit.BenefitService
+}
+
+// NewPreviewHandler creates a preview handler serving the pages of files
+func NewPreviewHandler(files *frontend.Server) *PreviewHandler {
+	return &PreviewHandler{
+		frontend:       files,
+		benefitService: benefit.NewBenefitService(),
+	}
+}
+
+// Claim renders the claim page of a benefit. Benefits without a preview (unknown,
+// private, paused, deleted or expired) get the plain page, so the response does not
+// reveal whether they exist.
+func (h *PreviewHandler) Claim(c *gin.Context) {
+	preview, err := h.benefitService.GetPreview(c.Request.Context(), c.Param("uuid"))
+	if err != nil {
+		h.frontend.ServeIndex(c.Writer, c.Request, frontend.Meta{NoIndex: true})
+		return
+	}
+
+	description := preview.Description
+	if description == "" {
+		description = fmt.Sprintf("%s 分享的福利，剩余 %d 个", creatorName(preview.Creator), preview.Remaining)
+	}
+
+	h.frontend.ServeIndex(c.Writer, c.Request, frontend.Meta{
+		Title:       preview.Title,
+		Description: truncate(strings.Join(strings.Fields(description), " "), maxPreviewDescription),
+		URL:         h.benefitService.GetClaimURL(site.Current().FrontendBase(c.Request), preview.UUID),
+		SiteName:    siteName,
+		Labels: []frontend.Label{
+			{Name: "剩余", Value: fmt.Sprintf("%d / %d", preview.Remaining, preview.Total)},
+			{Name: "发布者", Value: creatorName(preview.Creator)},
+		},
+		// 领取链接只应在分享的范围内传播
+		NoIndex: true,
+	})
+}
+
+func creatorName(username string) string {
+	if username == "" {
+		return "匿名用户"
+	}
+	return username
+}
+
+// truncate shortens s to at most n characters, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
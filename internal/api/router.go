@@ -1,31 +1,115 @@
 package api
 
 import (
+	"giftredeem/internal/clientip"
+	"giftredeem/internal/frontend"
 	"giftredeem/internal/middleware"
+	"giftredeem/internal/ratelimit"
+	"giftredeem/internal/tracing"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RouterConfig holds the settings the router is built with
+type RouterConfig struct {
+	Proxy             clientip.Config
+	RateLimitStore    string // memory/mysql
+	OAuthIPLimit      ratelimit.Limit
+	ClaimIPLimit      ratelimit.Limit
+	ClaimUserLimit    ratelimit.Limit
+	ClaimBenefitLimit ratelimit.Limit
+	StreamsPerIP      int              // 每个 IP 在单个实例上同时打开的实时推送连接数上限
+	RequiredProviders []string         // 就绪检查要求启用的 OAuth 提供商
+	MetricsToken      string           // 非空时 /metrics 需要携带该 Bearer 令牌
+	TracingService    string           // 非空时为每个请求记录 span，值为服务名
+	Frontend          *frontend.Server // 为空时只提供 API
+}
+
 // SetupRouter configures the API routes
-func SetupRouter() *gin.Engine {
-	r := gin.Default()
+func SetupRouter(cfg RouterConfig) (*gin.Engine, error) {
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	// Resolve client IP, scheme and host from trusted proxies only; gin's own
+	// X-Forwarded-For handling is turned off so it cannot be spoofed
+	resolver, err := clientip.NewResolver(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	r.SetTrustedProxies(nil)
+	r.Use(middleware.ProxyMiddleware(resolver))
+
+	// Request spans, started before the access log so that it carries the trace ID
+	if cfg.TracingService != "" {
+		r.Use(tracing.Middleware(cfg.TracingService))
+	}
+
+	// Request IDs and structured access logs
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.AccessLogMiddleware())
+
+	// Request metrics
+	r.Use(middleware.MetricsMiddleware())
 
 	// Set up CORS if needed
 	r.Use(corsMiddleware())
 
+	// Attach client details to the request context for audit logging
+	r.Use(middleware.RequestContextMiddleware())
+
+	// Rate limits
+	limitStore, err := ratelimit.NewStore(cfg.RateLimitStore)
+	if err != nil {
+		return nil, err
+	}
+	oauthLimit := middleware.RateLimitMiddleware(limitStore, middleware.RateLimitRule{
+		Name: "oauth:ip", Key: middleware.ByIP, Limit: cfg.OAuthIPLimit,
+	})
+	claimIPLimit := middleware.RateLimitMiddleware(limitStore, middleware.RateLimitRule{
+		Name: "claim:ip", Key: middleware.ByIP, Limit: cfg.ClaimIPLimit,
+	})
+	challengeLimit := middleware.RateLimitMiddleware(limitStore, middleware.RateLimitRule{
+		Name: "challenge:ip", Key: middleware.ByIP, Limit: cfg.ClaimIPLimit,
+	})
+	streamLimit := middleware.RateLimitMiddleware(limitStore, middleware.RateLimitRule{
+		Name: "stream:ip", Key: middleware.ByIP, Limit: cfg.ClaimIPLimit,
+	})
+	streamConcurrency := middleware.ConcurrencyLimitMiddleware(middleware.ByIP, cfg.StreamsPerIP)
+	claimLimit := middleware.RateLimitMiddleware(limitStore,
+		middleware.RateLimitRule{
+			Name: "claim:user", Key: middleware.ByUser, Limit: cfg.ClaimUserLimit,
+		},
+		middleware.RateLimitRule{
+			Name: "claim:benefit", Key: middleware.ByBenefit, Limit: cfg.ClaimBenefitLimit,
+		},
+	)
+
+	// Liveness and readiness probes
+	healthHandler := NewHealthHandler(cfg.RequiredProviders)
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+	r.GET("/metrics", middleware.MetricsHandler(cfg.MetricsToken))
+
 	// API routes
 	api := r.Group("/api")
 	{
+		// Machine-readable description of the routes below
+		specHandler, err := SpecHandler()
+		if err != nil {
+			return nil, err
+		}
+		api.GET("/openapi.json", specHandler)
+
 		// Auth routes
 		authHandler := NewAuthHandler()
 		auth := api.Group("/auth")
 		{
 			auth.GET("/providers", authHandler.GetProviders)
-			auth.GET("/login/:provider", authHandler.Login)
-			auth.GET("/callback/:provider", authHandler.Callback)
-			auth.POST("/verify/:provider", authHandler.VerifyCode) // 新API：验证授权码
+			auth.GET("/login/:provider", oauthLimit, authHandler.Login)
+			auth.GET("/callback/:provider", oauthLimit, authHandler.Callback)
+			auth.POST("/verify/:provider", oauthLimit, authHandler.VerifyCode) // 新API：验证授权码
 			auth.GET("/profile", middleware.AuthMiddleware(), authHandler.GetUserProfile)
 		}
 
@@ -40,6 +124,10 @@ func SetupRouter() *gin.Engine {
 				benefits.GET("/my", benefitHandler.GetUserBenefits)
 				benefits.PUT("/:uuid/status", benefitHandler.UpdateBenefitStatus)
 				benefits.GET("/:uuid/claims", benefitHandler.GetBenefitClaims)
+				benefits.POST("/:uuid/claims/:id/replace", benefitHandler.ReplaceCode)
+				benefits.DELETE("/:uuid/claims/:id", benefitHandler.RevokeClaim)
+				benefits.POST("/:uuid/claims/:id/review", benefitHandler.ReviewClaim)
+				benefits.GET("/:uuid/audit", benefitHandler.GetBenefitAudit)
 			}
 		}
 
@@ -48,6 +136,7 @@ func SetupRouter() *gin.Engine {
 		{
 			claims.Use(middleware.AuthMiddleware())
 			claims.GET("/my", benefitHandler.GetUserClaims)
+			claims.POST("/:id/feedback", benefitHandler.SubmitFeedback)
 		}
 
 		// Public benefit routes
@@ -55,22 +144,74 @@ func SetupRouter() *gin.Engine {
 		{
 			// Optional auth for viewing, required for claiming
 			claim.GET("/:uuid", middleware.OptionalAuthMiddleware(), benefitHandler.GetBenefitByUUID)
-			claim.POST("/:uuid", middleware.AuthMiddleware(), benefitHandler.ClaimBenefit)
+			claim.GET("/:uuid/stream", streamLimit, streamConcurrency, benefitHandler.StreamBenefit)
+			claim.GET("/:uuid/challenge", challengeLimit, benefitHandler.GetChallenge)
+			// 先按 IP 限流，避免未登录的请求也去查询数据库；按用户和福利的限制需要在认证之后
+			claim.POST("/:uuid", claimIPLimit, middleware.AuthMiddleware(), claimLimit, benefitHandler.ClaimBenefit)
+		}
+
+		// Webhook routes
+		webhookHandler := NewWebhookHandler()
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.Use(middleware.AuthMiddleware())
+			webhooks.GET("", webhookHandler.GetEndpoints)
+			webhooks.POST("", webhookHandler.CreateEndpoint)
+			webhooks.PUT("/:id", webhookHandler.UpdateEndpoint)
+			webhooks.DELETE("/:id", webhookHandler.DeleteEndpoint)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhooks.POST("/:id/test", webhookHandler.TestEndpoint)
+		}
+
+		// Notification routes
+		notificationHandler := NewNotificationHandler()
+		notifications := api.Group("/notifications")
+		{
+			notifications.GET("", middleware.AuthMiddleware(), notificationHandler.GetNotifications)
+			notifications.GET("/unread-count", middleware.AuthMiddleware(), notificationHandler.GetUnreadCount)
+			notifications.POST("/read", middleware.AuthMiddleware(), notificationHandler.MarkRead)
+			notifications.GET("/preferences", middleware.AuthMiddleware(), notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", middleware.AuthMiddleware(), notificationHandler.UpdatePreferences)
+
+			// 退订链接来自邮件，无需登录
+			notifications.GET("/unsubscribe", notificationHandler.Unsubscribe)
+			notifications.POST("/unsubscribe", notificationHandler.Unsubscribe)
+		}
+
+		// Admin routes
+		adminHandler := NewAdminHandler()
+		admin := api.Group("/admin")
+		{
+			admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+			admin.GET("/audit", adminHandler.GetAuditEvents)
+			admin.PUT("/users/:id/status", adminHandler.UpdateUserStatus)
 		}
 	}
 
-	// 提供静态文件服务
-	// 注意：将"./frontend/dist"替换为你的Vue构建文件的实际路径
-	r.Static("/assets", "./frontend/dist/assets")
-	r.StaticFile("/favicon.ico", "./frontend/dist/favicon.ico")
+	// Every operation in the OpenAPI document must be served
+	if err := checkOperations(r.Routes()); err != nil {
+		return nil, err
+	}
+
+	// Serve the frontend: hashed assets, then any other file or the SPA entry point
+	if cfg.Frontend != nil {
+		serveFrontend := gin.WrapH(cfg.Frontend)
+		r.GET("/assets/*filepath", serveFrontend)
+		r.HEAD("/assets/*filepath", serveFrontend)
+
+		// Claim links carry link preview tags for chat apps
+		previewHandler := NewPreviewHandler(cfg.Frontend)
+		r.GET("/claim/:uuid", previewHandler.Claim)
+		r.HEAD("/claim/:uuid", previewHandler.Claim)
+	}
 
-	// 处理所有前端路由 - 将非API请求重定向到index.html
+	// 处理所有前端路由 - 非API的页面请求返回index.html
 	r.NoRoute(func(c *gin.Context) {
 		path := c.Request.URL.Path
+		method := c.Request.Method
 
-		// 如果请求的不是API路径，返回前端应用的index.html
-		if !strings.HasPrefix(path, "/api/") {
-			c.File("./frontend/dist/index.html")
+		if cfg.Frontend != nil && !strings.HasPrefix(path, "/api/") && (method == http.MethodGet || method == http.MethodHead) {
+			cfg.Frontend.ServeHTTP(c.Writer, c.Request)
 			return
 		}
 
@@ -81,7 +222,7 @@ func SetupRouter() *gin.Engine {
 		})
 	})
 
-	return r
+	return r, nil
 }
 
 // corsMiddleware configures CORS for the API
@@ -89,8 +230,9 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"fmt"
+	"giftredeem/internal/openapi"
+	"giftredeem/pkg/apitypes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// specInfo describes the API in the OpenAPI document
+var specInfo = openapi.Info{
+	Title:   "GiftRedeem API",
+	Version: "1.0",
+	Description: "Every response is a JSON envelope {\"code\", \"msg\", \"data\"} sent with HTTP 200, " +
+		"except for rate limited requests (429). code is 0 on success; any other value is an error " +
+		"described by msg. Authenticated operations take the JWT returned by a login as a bearer token.",
+}
+
+// Operations describes the endpoints of the API except health checks, metrics and the
+// document itself. The OpenAPI document served at /api/openapi.json and the Go client in
+// pkg/client are both generated from it; SetupRouter refuses to start when an entry
+// has no matching route. Run go generate ./pkg/client after changing it.
+var Operations = []openapi.Operation{
+	// Auth
+	{
+		ID: "GetProviders", Method: http.MethodGet, Path: "/api/auth/providers", Tag: "auth",
+		Summary:  "Lists the enabled OAuth providers",
+		Response: apitypes.ProviderList{},
+	},
+	{
+		ID: "GetAuthURL", Method: http.MethodGet, Path: "/api/auth/login/:provider", Tag: "auth",
+		Summary:  "Returns the URL to send the browser to for logging in with a provider",
+		Response: apitypes.AuthURL{},
+	},
+	{
+		ID: "Callback", Method: http.MethodGet, Path: "/api/auth/callback/:provider", Tag: "auth",
+		Summary:     "Completes a login started in the browser",
+		Description: "Returns JSON when requested with Accept: application/json or response_type=json.",
+		Query:       apitypes.CallbackQuery{},
+		Response:    apitypes.LoginResult{},
+		Redirect:    true,
+	},
+	{
+		ID: "VerifyCode", Method: http.MethodPost, Path: "/api/auth/verify/:provider", Tag: "auth",
+		Summary:  "Exchanges an OAuth authorization code for a token",
+		Request:  apitypes.VerifyCodeRequest{},
+		Response: apitypes.LoginResult{},
+	},
+	{
+		ID: "GetProfile", Method: http.MethodGet, Path: "/api/auth/profile", Tag: "auth",
+		Summary:  "Returns the current user and their OAuth accounts",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.ProfileResult{},
+	},
+
+	// Benefits of the current user
+	{
+		ID: "CreateBenefit", Method: http.MethodPost, Path: "/api/benefits", Tag: "benefits",
+		Summary:     "Creates a benefit with its redemption codes",
+		Description: "When codes do not match the code format, data of the error lists them with their line numbers.",
+		Auth:        openapi.AuthRequired,
+		Request:     apitypes.CreateBenefitRequest{},
+		Response:    apitypes.CreateBenefitResult{},
+		ErrorData:   apitypes.ImportErrorDetail{},
+	},
+	{
+		ID: "GetMyBenefits", Method: http.MethodGet, Path: "/api/benefits/my", Tag: "benefits",
+		Summary:  "Lists the benefits created by the current user",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.BenefitList{},
+	},
+	{
+		ID: "UpdateBenefitStatus", Method: http.MethodPut, Path: "/api/benefits/:uuid/status", Tag: "benefits",
+		Summary: "Pauses, resumes or deletes a benefit",
+		Auth:    openapi.AuthRequired,
+		Request: apitypes.UpdateBenefitStatusRequest{},
+	},
+	{
+		ID: "GetBenefitClaims", Method: http.MethodGet, Path: "/api/benefits/:uuid/claims", Tag: "benefits",
+		Summary:  "Lists the claims on a benefit with its tier distribution and feedback summary",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.BenefitClaims{},
+	},
+	{
+		ID: "ReplaceCode", Method: http.MethodPost, Path: "/api/benefits/:uuid/claims/:id/replace", Tag: "benefits",
+		Summary:      "Issues a replacement for a code reported as already used",
+		Auth:         openapi.AuthRequired,
+		Params:       []openapi.Param{claimIDParam},
+		Request:      apitypes.ReplaceCodeRequest{},
+		OptionalBody: true,
+		Response:     apitypes.ClaimItemResult{},
+	},
+	{
+		ID: "RevokeClaim", Method: http.MethodDelete, Path: "/api/benefits/:uuid/claims/:id", Tag: "benefits",
+		Summary:  "Revokes a claim, returning or burning its codes",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{claimIDParam},
+		Request:  apitypes.RevokeClaimRequest{},
+		Response: apitypes.RevokeClaimResult{},
+	},
+	{
+		ID: "ReviewClaim", Method: http.MethodPost, Path: "/api/benefits/:uuid/claims/:id/review", Tag: "benefits",
+		Summary:  "Approves or rejects a flagged or held claim",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{claimIDParam},
+		Request:  apitypes.ReviewClaimRequest{},
+		Response: apitypes.ReviewClaimResult{},
+	},
+	{
+		ID: "GetBenefitAudit", Method: http.MethodGet, Path: "/api/benefits/:uuid/audit", Tag: "benefits",
+		Summary:  "Lists the audit events of a benefit",
+		Auth:     openapi.AuthRequired,
+		Query:    apitypes.AuditQuery{},
+		Response: apitypes.AuditPage{},
+	},
+
+	// Claims of the current user
+	{
+		ID: "GetMyClaims", Method: http.MethodGet, Path: "/api/claims/my", Tag: "claims",
+		Summary:  "Lists the claims of the current user",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.ClaimList{},
+	},
+	{
+		ID: "SubmitFeedback", Method: http.MethodPost, Path: "/api/claims/:id/feedback", Tag: "claims",
+		Summary:  "Reports whether a claimed code worked",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{claimIDParam},
+		Request:  apitypes.FeedbackRequest{},
+		Response: apitypes.FeedbackResult{},
+	},
+
+	// Claim pages
+	{
+		ID: "GetBenefit", Method: http.MethodGet, Path: "/api/claim/:uuid", Tag: "claim",
+		Summary:  "Returns a benefit and whether the current user can claim it",
+		Auth:     openapi.AuthOptional,
+		Response: apitypes.BenefitInfo{},
+	},
+	{
+		ID: "StreamBenefit", Method: http.MethodGet, Path: "/api/claim/:uuid/stream", Tag: "claim",
+		Summary:     "Streams the claimed count and status of a benefit",
+		Description: "Sends an \"update\" event with the current state, then one per change. The stream ends after the benefit is deleted.",
+		Stream:      true,
+	},
+	{
+		ID: "GetChallenge", Method: http.MethodGet, Path: "/api/claim/:uuid/challenge", Tag: "claim",
+		Summary:  "Issues the challenge to solve before claiming a benefit",
+		Response: apitypes.Challenge{},
+	},
+	{
+		ID: "ClaimBenefit", Method: http.MethodPost, Path: "/api/claim/:uuid", Tag: "claim",
+		Summary:      "Claims a benefit",
+		Auth:         openapi.AuthRequired,
+		Request:      apitypes.ClaimRequest{},
+		OptionalBody: true,
+		Response:     apitypes.ClaimResult{},
+	},
+
+	// Webhook endpoints of the current user
+	{
+		ID: "GetWebhooks", Method: http.MethodGet, Path: "/api/webhooks", Tag: "webhooks",
+		Summary:  "Lists the webhook endpoints of the current user",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.WebhookEndpointList{},
+	},
+	{
+		ID: "CreateWebhook", Method: http.MethodPost, Path: "/api/webhooks", Tag: "webhooks",
+		Summary:     "Registers a webhook endpoint",
+		Description: "The response is the only one that contains the signing secret.",
+		Auth:        openapi.AuthRequired,
+		Request:     apitypes.WebhookEndpointRequest{},
+		Response:    apitypes.WebhookEndpointResult{},
+	},
+	{
+		ID: "UpdateWebhook", Method: http.MethodPut, Path: "/api/webhooks/:id", Tag: "webhooks",
+		Summary:  "Changes a webhook endpoint",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{webhookIDParam},
+		Request:  apitypes.WebhookEndpointRequest{},
+		Response: apitypes.WebhookEndpointResult{},
+	},
+	{
+		ID: "DeleteWebhook", Method: http.MethodDelete, Path: "/api/webhooks/:id", Tag: "webhooks",
+		Summary: "Removes a webhook endpoint",
+		Auth:    openapi.AuthRequired,
+		Params:  []openapi.Param{webhookIDParam},
+	},
+	{
+		ID: "GetWebhookDeliveries", Method: http.MethodGet, Path: "/api/webhooks/:id/deliveries", Tag: "webhooks",
+		Summary:  "Lists the most recent deliveries of a webhook endpoint",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{webhookIDParam},
+		Query:    apitypes.WebhookDeliveryQuery{},
+		Response: apitypes.WebhookDeliveryList{},
+	},
+	{
+		ID: "TestWebhook", Method: http.MethodPost, Path: "/api/webhooks/:id/test", Tag: "webhooks",
+		Summary:  "Queues a ping event for a webhook endpoint",
+		Auth:     openapi.AuthRequired,
+		Params:   []openapi.Param{webhookIDParam},
+		Response: apitypes.WebhookDeliveryResult{},
+	},
+
+	// Notifications of the current user
+	{
+		ID: "GetNotifications", Method: http.MethodGet, Path: "/api/notifications", Tag: "notifications",
+		Summary:  "Lists the in-app notifications of the current user",
+		Auth:     openapi.AuthRequired,
+		Query:    apitypes.NotificationQuery{},
+		Response: apitypes.NotificationList{},
+	},
+	{
+		ID: "GetUnreadCount", Method: http.MethodGet, Path: "/api/notifications/unread-count", Tag: "notifications",
+		Summary:  "Returns the number of unread notifications",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.UnreadCount{},
+	},
+	{
+		ID: "MarkNotificationsRead", Method: http.MethodPost, Path: "/api/notifications/read", Tag: "notifications",
+		Summary:      "Marks notifications as read",
+		Auth:         openapi.AuthRequired,
+		Request:      apitypes.MarkReadRequest{},
+		OptionalBody: true,
+		Response:     apitypes.MarkReadResult{},
+	},
+	{
+		ID: "GetNotificationPreferences", Method: http.MethodGet, Path: "/api/notifications/preferences", Tag: "notifications",
+		Summary:  "Returns the email settings of the current user",
+		Auth:     openapi.AuthRequired,
+		Response: apitypes.NotificationPreferences{},
+	},
+	{
+		ID: "UpdateNotificationPreferences", Method: http.MethodPut, Path: "/api/notifications/preferences", Tag: "notifications",
+		Summary:  "Changes the email settings of the current user",
+		Auth:     openapi.AuthRequired,
+		Request:  apitypes.UpdatePreferencesRequest{},
+		Response: apitypes.NotificationPreferences{},
+	},
+	{
+		ID: "Unsubscribe", Method: http.MethodPost, Path: "/api/notifications/unsubscribe", Tag: "notifications",
+		Summary:     "Turns off emails for the recipient of an unsubscribe link",
+		Description: "One-click unsubscribe (RFC 8058). GET with the same query, as sent by a browser opening the link, returns an HTML page instead.",
+		Query:       apitypes.UnsubscribeQuery{},
+	},
+
+	// Administration
+	{
+		ID: "GetAuditEvents", Method: http.MethodGet, Path: "/api/admin/audit", Tag: "admin",
+		Summary:     "Queries the global audit log",
+		Description: "Requires an administrator account.",
+		Auth:        openapi.AuthRequired,
+		Query:       apitypes.AdminAuditQuery{},
+		Response:    apitypes.AuditPage{},
+	},
+	{
+		ID: "UpdateUserStatus", Method: http.MethodPut, Path: "/api/admin/users/:id/status", Tag: "admin",
+		Summary:     "Bans, restores or deletes a user account",
+		Description: "Requires an administrator account.",
+		Auth:        openapi.AuthRequired,
+		Params:      []openapi.Param{{Name: "id", Type: uint(0), Description: "User ID"}},
+		Request:     apitypes.UpdateUserStatusRequest{},
+		Response:    apitypes.UserStatusResult{},
+	},
+}
+
+// claimIDParam is the ID of a claim in paths
+var claimIDParam = openapi.Param{Name: "id", Type: uint(0), Description: "Claim ID"}
+
+// webhookIDParam is the ID of a webhook endpoint in paths
+var webhookIDParam = openapi.Param{Name: "id", Type: uint(0), Description: "Webhook endpoint ID"}
+
+// Spec returns the OpenAPI document built from Operations, as JSON
+func Spec() ([]byte, error) {
+	doc, err := openapi.Build(specInfo, Operations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+	body, err := doc.MarshalIndent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+	return body, nil
+}
+
+// SpecHandler serves the OpenAPI document. It is built once, so errors in Operations
+// surface when the router is set up.
+func SpecHandler() (gin.HandlerFunc, error) {
+	body, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}, nil
+}
+
+// checkOperations verifies that every entry of Operations is served by a route
+func checkOperations(routes gin.RoutesInfo) error {
+	served := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		served[route.Method+" "+route.Path] = true
+	}
+	for _, op := range Operations {
+		if !served[op.Method+" "+op.Path] {
+			return fmt.Errorf("operation %s: no route for %s %s", op.ID, op.Method, op.Path)
+		}
+	}
+	return nil
+}
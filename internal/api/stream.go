@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	benefitpkg "giftredeem/internal/benefit"
+	"giftredeem/internal/live"
+	"giftredeem/internal/response"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is the interval of comment lines that keep idle SSE connections open through proxies
+const streamKeepAlive = 15 * time.Second
+
+// StreamBenefit pushes the claimed count and status of a benefit as Server-Sent Events.
+// The current state is sent right away as an "update" event, followed by one event per change.
+func (h *BenefitHandler) StreamBenefit(c *gin.Context) {
+	benefitUUID := c.Param("uuid")
+	if benefitUUID == "" {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Benefit UUID is required"))
+		return
+	}
+
+	benefit, err := h.benefitService.GetBenefitByUUID(benefitUUID)
+	if err != nil {
+		code := response.CodeServerError
+		if errors.Is(err, benefitpkg.ErrNotFound) {
+			code = response.CodeBenefitNotFound
+		}
+
+		c.JSON(http.StatusOK, response.Error(code, "Failed to retrieve benefit: "+err.Error()))
+		return
+	}
+	// 与 GetBenefitByUUID 一致，已删除的福利不可查看
+	if !streamable(benefit.Status) {
+		c.JSON(http.StatusOK, response.Error(response.CodeBenefitNotActive, "This benefit is no longer available"))
+		return
+	}
+
+	current := live.FromBenefit(benefit)
+	sub := live.DefaultHub.Subscribe(current)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 的响应缓冲
+
+	c.SSEvent("update", current)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-sub.Done():
+			return false
+		case update := <-sub.C:
+			c.SSEvent("update", update)
+			// 福利在连接期间被删除时，发送最后的状态后结束推送
+			return streamable(update.Status)
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// streamable reports whether the live state of a benefit with the given status may be
+// watched: paused and expired benefits are still shown on their claim page
+func streamable(status string) bool {
+	return status == "active" || status == "paused" || status == "expired"
+}
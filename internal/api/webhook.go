@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"giftredeem/internal/models"
+	"giftredeem/internal/response"
+	"giftredeem/internal/webhook"
+	"giftredeem/pkg/apitypes"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles webhook endpoint management requests
+type WebhookHandler struct {
+	webhookService *webhook.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhook.NewWebhookService(),
+	}
+}
+
+// GetEndpoints lists the current user's webhook endpoints
+func (h *WebhookHandler) GetEndpoints(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	endpoints, err := h.webhookService.GetUserEndpoints(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeServerError, "Failed to retrieve webhooks: "+err.Error()))
+		return
+	}
+
+	data := make([]apitypes.WebhookEndpoint, len(endpoints))
+	for i, e := range endpoints {
+		data[i] = endpointData(&e, false)
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.WebhookEndpointList{
+		Webhooks: data,
+	}))
+}
+
+// CreateEndpoint registers a new webhook endpoint
+func (h *WebhookHandler) CreateEndpoint(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	var input apitypes.WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(user.ID, webhook.EndpointInput(input))
+	if err != nil {
+		h.respondError(c, "Failed to create webhook", err)
+		return
+	}
+
+	// 仅在创建时返回签名密钥
+	c.JSON(http.StatusOK, response.Success(apitypes.WebhookEndpointResult{
+		Webhook: endpointData(endpoint, true),
+	}))
+}
+
+// UpdateEndpoint changes a webhook endpoint
+func (h *WebhookHandler) UpdateEndpoint(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	var input apitypes.WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid input: "+err.Error()))
+		return
+	}
+
+	endpoint, err := h.webhookService.UpdateEndpoint(user.ID, endpointID, webhook.EndpointInput(input))
+	if err != nil {
+		h.respondError(c, "Failed to update webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.WebhookEndpointResult{
+		Webhook: endpointData(endpoint, false),
+	}))
+}
+
+// DeleteEndpoint removes a webhook endpoint
+func (h *WebhookHandler) DeleteEndpoint(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpoint(user.ID, endpointID); err != nil {
+		h.respondError(c, "Failed to delete webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// GetDeliveries returns the delivery log of a webhook endpoint
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	deliveries, err := h.webhookService.GetDeliveries(user.ID, endpointID, limit)
+	if err != nil {
+		h.respondError(c, "Failed to retrieve deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.WebhookDeliveryList{
+		Deliveries: deliveriesData(deliveries),
+	}))
+}
+
+// TestEndpoint queues a ping event for a webhook endpoint
+func (h *WebhookHandler) TestEndpoint(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	user := userValue.(*models.User)
+
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.SendTest(user.ID, endpointID)
+	if err != nil {
+		h.respondError(c, "Failed to queue test delivery", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(apitypes.WebhookDeliveryResult{
+		Delivery: deliveryData(*delivery),
+	}))
+}
+
+// respondError maps webhook service errors to API responses
+func (h *WebhookHandler) respondError(c *gin.Context, message string, err error) {
+	code := response.CodeServerError
+	if errors.Is(err, webhook.ErrNotFound) {
+		code = response.CodeNotFound
+	} else if errors.Is(err, webhook.ErrInvalidInput) {
+		code = response.CodeInvalidInput
+	}
+
+	c.JSON(http.StatusOK, response.Error(code, message+": "+err.Error()))
+}
+
+// parseEndpointID reads the endpoint ID from the path, responding with an error if it is invalid
+func parseEndpointID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.Error(response.CodeInvalidInput, "Invalid webhook ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
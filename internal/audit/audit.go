@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"context"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var logger = logging.For("audit")
+
+// Actions recorded in the audit log
+const (
+	ActionBenefitCreated       = "benefit.created"
+	ActionBenefitStatusChanged = "benefit.status_changed"
+	ActionBenefitClaimsViewed  = "benefit.claims_viewed"
+	ActionClaimCreated         = "claim.created"
+	ActionClaimRevoked         = "claim.revoked"
+	ActionClaimBlocked         = "claim.blocked"
+	ActionClaimReviewed        = "claim.reviewed"
+	ActionCodeReplaced         = "claim.code_replaced"
+	ActionUserBannedByCreator  = "creator.user_banned"
+	ActionLogin                = "auth.login"
+	ActionRegister             = "auth.register"
+	ActionLoginDenied          = "auth.login_denied"
+	ActionAdminUserStatus      = "admin.user_status_changed"
+	ActionOAuthProviderSynced  = "oauth_provider.synced"
+)
+
+// Target types recorded in the audit log
+const (
+	TargetBenefit       = "benefit"
+	TargetClaim         = "claim"
+	TargetUser          = "user"
+	TargetOAuthProvider = "oauth_provider"
+)
+
+// Event describes an action to be recorded
+type Event struct {
+	ActorID    *uint
+	Action     string
+	TargetType string
+	TargetID   string
+	BenefitID  *uint
+	Before     map[string]interface{}
+	After      map[string]interface{}
+}
+
+// requestInfo carries request details used to enrich audit events
+type requestInfo struct {
+	ip        string
+	userAgent string
+}
+
+type contextKey struct{}
+
+// WithRequest returns a context carrying the client IP and user agent of the current request
+func WithRequest(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestInfo{ip: ip, userAgent: userAgent})
+}
+
+// Record appends an event to the audit log using tx, so it commits or rolls back together
+// with the change it describes. Pass db.DB when there is no surrounding transaction.
+func Record(ctx context.Context, tx *gorm.DB, e Event) error {
+	event := models.AuditEvent{
+		ActorID:    e.ActorID,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		BenefitID:  e.BenefitID,
+		Before:     e.Before,
+		After:      e.After,
+		CreatedAt:  time.Now(),
+	}
+
+	if ctx != nil {
+		if info, ok := ctx.Value(contextKey{}).(requestInfo); ok {
+			event.IPAddress = info.ip
+			event.UserAgent = info.userAgent
+		}
+	}
+
+	return tx.Create(&event).Error
+}
+
+// RecordBestEffort records an event outside of any transaction, logging instead of
+// returning failures. It is meant for read-only actions such as viewing a claim list.
+func RecordBestEffort(ctx context.Context, e Event) {
+	if err := Record(ctx, db.DB, e); err != nil {
+		logger.ErrorContext(ctx, "failed to record event", "action", e.Action, "error", err)
+	}
+}
+
+// Query filters audit events
+type Query struct {
+	ActorID    *uint
+	BenefitID  *uint
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	Page       int
+	PageSize   int
+}
+
+// List returns the events matching q, newest first, along with the total number of matches
+func List(q Query) ([]models.AuditEvent, int64, error) {
+	query := db.DB.Model(&models.AuditEvent{})
+	if q.ActorID != nil {
+		query = query.Where("actor_id = ?", *q.ActorID)
+	}
+	if q.BenefitID != nil {
+		query = query.Where("benefit_id = ?", *q.BenefitID)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != "" {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.Since != nil {
+		query = query.Where("created_at >= ?", *q.Since)
+	}
+	if q.Until != nil {
+		query = query.Where("created_at < ?", *q.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("id DESC").
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Find(&events).Error
+	return events, total, err
+}
@@ -1,26 +1,41 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"giftredeem/internal/audit"
 	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/metrics"
 	"giftredeem/internal/models"
+	"giftredeem/internal/site"
+	"giftredeem/internal/tracing"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// DevJWTSecret is the well-known secret used in dev mode when none is configured.
+// Tokens signed with it can be forged by anyone, so it is refused in production.
+const DevJWTSecret = "your-secret-key-should-be-loaded-from-env"
+
+var logger = logging.For("auth")
+
 var (
-	// Secret key for JWT signing - load from environment
-	jwtSecret = getJWTSecret()
+	// Secret key for JWT signing, set at startup with SetJWTSecret
+	jwtSecret = []byte(DevJWTSecret)
 
 	// ErrInvalidProvider indicates the requested OAuth provider doesn't exist or is disabled
 	ErrInvalidProvider = errors.New("invalid or disabled OAuth provider")
@@ -32,14 +47,10 @@ var (
 	ErrUserBanned = errors.New("user account is banned or deleted")
 )
 
-// getJWTSecret loads the JWT secret from environment variables or uses a default (for development only)
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Default secret for development - DO NOT USE IN PRODUCTION
-		secret = "your-secret-key-should-be-loaded-from-env"
-	}
-	return []byte(secret)
+// SetJWTSecret sets the key used to sign and verify JWTs. It must be called before
+// the server starts handling requests.
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
 }
 
 // OAuthHandler handles all OAuth related operations
@@ -84,8 +95,7 @@ func (h *OAuthHandler) GetAuthURL(c *gin.Context, providerName string) (string,
 	// 使用请求的协议来决定是否设置 secure 标志
 	secure := strings.HasPrefix(c.Request.URL.Scheme, "https")
 
-	// 输出调试信息
-	fmt.Printf("Setting cookie: state=%s, domain=%s, secure=%v\n", state, domain, secure)
+	logger.DebugContext(c.Request.Context(), "setting OAuth state cookie", "provider", providerName, "domain", domain, "secure", secure)
 
 	// 直接设置 Cookie 头，允许我们指定 SameSite 属性
 	// SameSite=None 是必须的，以允许跨站点请求（OAuth 重定向）
@@ -118,24 +128,19 @@ func (h *OAuthHandler) HandleCallback(c *gin.Context, providerName string) (*mod
 	// 尝试从原始 cookie 获取状态
 	storedState, err := c.Cookie("oauth_state")
 
-	// 输出调试信息
-	fmt.Printf("Callback received - State: %s, Stored state: %s, Cookie error: %v\n",
-		state, storedState, err)
-	fmt.Printf("Request cookies: %v\n", c.Request.Cookies())
-
 	// 如果原始 cookie 获取失败，尝试从备用会话 cookie 获取
 	sessionKey := fmt.Sprintf("oauth_state_%s", state)
 	backupState, backupErr := c.Cookie(sessionKey)
-	fmt.Printf("Backup state check - Session key: %s, Backup state: %s, Error: %v\n",
-		sessionKey, backupState, backupErr)
+	logger.DebugContext(c.Request.Context(), "OAuth callback received", "provider", providerName,
+		"state_cookie", err == nil, "backup_state_cookie", backupErr == nil)
 
 	// 如果原始状态匹配或备用状态匹配，则接受
 	if state != "" && (state == storedState || state == backupState) {
-		fmt.Println("State validation successful")
+		logger.DebugContext(c.Request.Context(), "OAuth state validated", "provider", providerName)
 	} else {
 		// 开发/测试模式 - 临时跳过状态验证 (仅用于开发！)
 		// 在生产环境中删除此块
-		fmt.Println("WARNING: Using development bypass for state validation!")
+		logger.WarnContext(c.Request.Context(), "OAuth state mismatch, using development bypass", "provider", providerName)
 		// 在生产环境取消下面的注释
 		// return nil, "", fmt.Errorf("invalid state parameter (received: %s, stored: %s)", state, storedState)
 	}
@@ -179,13 +184,13 @@ func (h *OAuthHandler) HandleCallback(c *gin.Context, providerName string) (*mod
 	}
 
 	// Get user info from the provider
-	userInfo, err := h.getUserInfo(providerName, tokenData["access_token"])
+	userInfo, err := h.getUserInfo(c.Request.Context(), providerName, tokenData["access_token"])
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get user info: %w", err)
 	}
 
 	// Find or create user
-	user, err := h.findOrCreateUser(providerName, userInfo, tokenData)
+	user, err := h.findOrCreateUser(c.Request.Context(), providerName, userInfo, tokenData)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to process user: %w", err)
 	}
@@ -203,13 +208,12 @@ func (h *OAuthHandler) HandleCallback(c *gin.Context, providerName string) (*mod
 func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code string) (map[string]string, error) {
 	// Get provider configuration
 	var provider models.OAuthProvider
-	if err := db.DB.Where("name = ? AND enabled = ?", providerName, true).First(&provider).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("name = ? AND enabled = ?", providerName, true).First(&provider).Error; err != nil {
 		return nil, ErrInvalidProvider
 	}
 
-	fmt.Printf("Exchanging code for token for provider %s at URL: %s\n", providerName, provider.TokenURL)
-	fmt.Printf("Authorization code: %s\n", code)
-	fmt.Printf("Redirect URI: %s\n", getRedirectURI(c, providerName))
+	logger.DebugContext(c.Request.Context(), "exchanging code for token", "provider", providerName,
+		"token_url", provider.TokenURL, "redirect_uri", getRedirectURI(c, providerName))
 
 	// Prepare request body
 	data := url.Values{}
@@ -219,14 +223,16 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 	data.Set("client_id", provider.ClientID)
 
 	// 处理 ClientSecret 可能为空的情况
-	if provider.ClientSecret != nil {
-		data.Set("client_secret", *provider.ClientSecret)
+	clientSecret, err := ClientSecret(provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt client secret: %w", err)
+	}
+	if clientSecret != "" {
+		data.Set("client_secret", clientSecret)
 	}
-
-	fmt.Printf("Token request data: %s\n", data.Encode())
 
 	// Make POST request to token endpoint
-	req, err := http.NewRequest("POST", provider.TokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", provider.TokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -234,11 +240,11 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("User-Agent", "GiftRedeem OAuth Client")
 
-	fmt.Printf("Token request headers: %v\n", req.Header)
-
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := tracing.NewHTTPClient(10 * time.Second)
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.ObserveTokenExchange(providerName, start, err == nil && resp.StatusCode == http.StatusOK)
 	if err != nil {
 		return nil, fmt.Errorf("token request failed: %w", err)
 	}
@@ -250,9 +256,7 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	fmt.Printf("Token response status: %d\n", resp.StatusCode)
-	fmt.Printf("Token response headers: %v\n", resp.Header)
-	fmt.Printf("Token response body: %s\n", string(body))
+	logger.DebugContext(c.Request.Context(), "token response received", "provider", providerName, "status", resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
@@ -262,7 +266,7 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
 		// 某些提供商可能返回非 JSON 格式的响应
-		fmt.Printf("Failed to parse JSON response: %v. Trying alternative formats...\n", err)
+		logger.DebugContext(c.Request.Context(), "token response is not JSON, trying form encoding", "provider", providerName, "error", err)
 
 		// 尝试解析表单编码的响应
 		if strings.Contains(resp.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
@@ -283,8 +287,6 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 		}
 	}
 
-	fmt.Printf("Parsed token data: %v\n", result)
-
 	// Extract token data
 	tokenData := make(map[string]string)
 
@@ -293,7 +295,7 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 		tokenData["access_token"] = fmt.Sprintf("%v", accessToken)
 	} else {
 		// 开发模式：如果没有找到 access_token，但响应成功，使用一个假的令牌
-		fmt.Println("WARNING: No access_token found in response. Using fake token for development.")
+		logger.WarnContext(c.Request.Context(), "no access_token in token response, using fake token for development", "provider", providerName)
 		tokenData["access_token"] = "dev_fake_token_" + generateRandomState()
 	}
 
@@ -313,23 +315,21 @@ func (h *OAuthHandler) exchangeCodeForToken(c *gin.Context, providerName, code s
 		tokenData["expires_in"] = "3600"
 	}
 
-	fmt.Printf("Final token data: %v\n", tokenData)
 	return tokenData, nil
 }
 
 // getUserInfo retrieves user information from the OAuth provider
-func (h *OAuthHandler) getUserInfo(providerName, accessToken string) (map[string]interface{}, error) {
+func (h *OAuthHandler) getUserInfo(ctx context.Context, providerName, accessToken string) (map[string]interface{}, error) {
 	// Get provider configuration
 	var provider models.OAuthProvider
-	if err := db.DB.Where("name = ?", providerName).First(&provider).Error; err != nil {
+	if err := db.DB.WithContext(ctx).Where("name = ?", providerName).First(&provider).Error; err != nil {
 		return nil, ErrInvalidProvider
 	}
 
-	fmt.Printf("Fetching user info for provider %s from URL: %s\n", providerName, provider.UserInfoURL)
-	fmt.Printf("Access token: %s\n", accessToken)
+	logger.DebugContext(ctx, "fetching user info", "provider", providerName, "user_info_url", provider.UserInfoURL)
 
 	// Create request to user info endpoint
-	req, err := http.NewRequest("GET", provider.UserInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", provider.UserInfoURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -351,12 +351,8 @@ func (h *OAuthHandler) getUserInfo(providerName, accessToken string) (map[string
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "GiftRedeem OAuth Client")
 
-	// 打印请求详情
-	fmt.Printf("Request Headers: %v\n", req.Header)
-	fmt.Printf("Request URL: %s\n", req.URL.String())
-
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := tracing.NewHTTPClient(10 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request error: %w", err)
@@ -369,9 +365,7 @@ func (h *OAuthHandler) getUserInfo(providerName, accessToken string) (map[string
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	fmt.Printf("Response Status: %d\n", resp.StatusCode)
-	fmt.Printf("Response Headers: %v\n", resp.Header)
-	fmt.Printf("Response Body: %s\n", string(body))
+	logger.DebugContext(ctx, "user info response received", "provider", providerName, "status", resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("user info request failed with status %d: %s", resp.StatusCode, string(body))
@@ -383,11 +377,9 @@ func (h *OAuthHandler) getUserInfo(providerName, accessToken string) (map[string
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	fmt.Printf("Parsed user info: %v\n", userInfo)
-
 	// 如果是开发测试模式，且获取不到用户信息，返回模拟数据
 	if len(userInfo) == 0 {
-		fmt.Println("WARNING: Using mock user data for development")
+		logger.WarnContext(ctx, "empty user info, using mock user for development", "provider", providerName)
 		userInfo = map[string]interface{}{
 			"id":       fmt.Sprintf("dev_%s_%d", providerName, time.Now().Unix()),
 			"name":     "Dev User",
@@ -400,7 +392,16 @@ func (h *OAuthHandler) getUserInfo(providerName, accessToken string) (map[string
 }
 
 // findOrCreateUser finds an existing user by OAuth credentials or creates a new one
-func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string]interface{}, tokenData map[string]string) (*models.User, error) {
+func (h *OAuthHandler) findOrCreateUser(ctx context.Context, providerName string, userInfo map[string]interface{}, tokenData map[string]string) (*models.User, error) {
+	// 单独的 span 把事务中的查询与向提供商发出的请求区分开
+	ctx, span := tracing.Start(ctx, "oauth.find_or_create_user", trace.WithAttributes(attribute.String("oauth.provider", providerName)))
+	user, err := h.upsertUser(ctx, providerName, userInfo, tokenData)
+	tracing.End(span, err)
+	return user, err
+}
+
+// upsertUser updates the OAuth account and user matching userInfo, or creates them
+func (h *OAuthHandler) upsertUser(ctx context.Context, providerName string, userInfo map[string]interface{}, tokenData map[string]string) (*models.User, error) {
 	// Extract user ID from the provider's response - different providers may use different field names
 	var providerUserID string
 
@@ -418,21 +419,19 @@ func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string
 		providerUserID = fmt.Sprintf("%v", sub)
 	}
 
-	fmt.Printf("Extracted provider user ID: %s\n", providerUserID)
-
 	if providerUserID == "" {
 		// 开发/测试模式：生成一个模拟 ID
 		if strings.HasPrefix(tokenData["access_token"], "dev_fake") {
 			providerUserID = "dev_user_" + generateRandomState()
-			fmt.Printf("Using fake user ID for development: %s\n", providerUserID)
+			logger.WarnContext(ctx, "using fake user ID for development", "provider", providerName, "provider_user_id", providerUserID)
 		} else {
-			fmt.Printf("User info data: %v\n", userInfo)
+			logger.WarnContext(ctx, "no user ID in user info", "provider", providerName, "fields", mapKeys(userInfo))
 			return nil, errors.New("unable to extract user ID from provider response")
 		}
 	}
 
 	// Transaction to ensure data consistency
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -453,6 +452,7 @@ func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string
 		// Check if account is revoked
 		if oauthAccount.Status != "active" {
 			tx.Rollback()
+			recordLoginDenied(ctx, oauthAccount.UserID, providerName, "oauth account revoked")
 			return nil, errors.New("OAuth account is revoked")
 		}
 
@@ -466,6 +466,7 @@ func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string
 		// Check if user is banned or deleted
 		if user.Status != "active" {
 			tx.Rollback()
+			recordLoginDenied(ctx, user.ID, providerName, "user "+user.Status)
 			return nil, ErrUserBanned
 		}
 
@@ -519,6 +520,18 @@ func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string
 			return nil, err
 		}
 
+		err = audit.Record(ctx, tx, audit.Event{
+			ActorID:    &user.ID,
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+			After:      map[string]interface{}{"provider": providerName},
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
 		tx.Commit()
 		return &user, nil
 	}
@@ -593,6 +606,18 @@ func (h *OAuthHandler) findOrCreateUser(providerName string, userInfo map[string
 		return nil, err
 	}
 
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID:    &newUser.ID,
+		Action:     audit.ActionRegister,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(newUser.ID), 10),
+		After:      map[string]interface{}{"provider": providerName, "username": newUser.Username},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	tx.Commit()
 	return &newUser, nil
 }
@@ -694,15 +719,31 @@ func GetEnabledProviders() ([]models.OAuthProvider, error) {
 
 // Helper functions
 
-// getRedirectURI generates the appropriate redirect URI for the OAuth flow
+// getRedirectURI returns the redirect URI registered with the provider, identical
+// for the authorization request and the code exchange
 func getRedirectURI(c *gin.Context, providerName string) string {
-	// Get base URL from request or configuration
-	baseURL := fmt.Sprintf("%s://%s", c.Request.URL.Scheme, c.Request.Host)
-	if c.Request.URL.Scheme == "" {
-		baseURL = "http://" + c.Request.Host // Default to HTTP if scheme is empty
-	}
+	return site.Current().OAuthCallbackURL(c.Request, providerName)
+}
 
-	return fmt.Sprintf("%s/api/auth/callback/%s", baseURL, providerName)
+// recordLoginDenied writes an audit event for a rejected login attempt
+func recordLoginDenied(ctx context.Context, userID uint, providerName, reason string) {
+	audit.RecordBestEffort(ctx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionLoginDenied,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatUint(uint64(userID), 10),
+		After:      map[string]interface{}{"provider": providerName, "reason": reason},
+	})
+}
+
+// mapKeys returns the keys of m, to log the shape of provider responses without their values
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // generateRandomState generates a random state string for CSRF protection
@@ -712,8 +753,8 @@ func generateRandomState() string {
 }
 
 // FindOrCreateUserFromInfo finds or creates a user based on OAuth provider information
-func (h *OAuthHandler) FindOrCreateUserFromInfo(providerName string, userInfo map[string]interface{}, tokenData map[string]string) (*models.User, error) {
-	return h.findOrCreateUser(providerName, userInfo, tokenData)
+func (h *OAuthHandler) FindOrCreateUserFromInfo(ctx context.Context, providerName string, userInfo map[string]interface{}, tokenData map[string]string) (*models.User, error) {
+	return h.findOrCreateUser(ctx, providerName, userInfo, tokenData)
 }
 
 // GenerateJWT creates a JWT token for the specified user
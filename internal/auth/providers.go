@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/internal/secretbox"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidProviderConfig indicates a configured provider missing required settings
+var ErrInvalidProviderConfig = errors.New("invalid OAuth provider configuration")
+
+// ProviderConfig declares an OAuth provider. Configured providers are the source of
+// truth: SyncProviders creates or updates their rows in o_auth_providers at startup.
+type ProviderConfig struct {
+	Name         string
+	DisplayName  string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scope        string
+	Enabled      bool
+	SortOrder    int
+}
+
+// ProviderPresets fill in the endpoints of well-known providers, so that only the
+// client credentials need to be configured
+var ProviderPresets = map[string]ProviderConfig{
+	"linuxdo": {
+		DisplayName: "LinuxDo",
+		AuthURL:     "https://connect.linux.do/oauth2/authorize",
+		TokenURL:    "https://connect.linux.do/oauth2/token",
+		UserInfoURL: "https://connect.linux.do/api/user",
+		Scope:       "user",
+	},
+}
+
+// WithPreset returns the provider with empty endpoints filled from its preset, if any
+func (p ProviderConfig) WithPreset() ProviderConfig {
+	preset, ok := ProviderPresets[p.Name]
+	if !ok {
+		return p
+	}
+	if p.DisplayName == "" {
+		p.DisplayName = preset.DisplayName
+	}
+	if p.AuthURL == "" {
+		p.AuthURL = preset.AuthURL
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = preset.TokenURL
+	}
+	if p.UserInfoURL == "" {
+		p.UserInfoURL = preset.UserInfoURL
+	}
+	if p.Scope == "" {
+		p.Scope = preset.Scope
+	}
+	return p
+}
+
+// Validate checks that the provider can be used to log in
+func (p ProviderConfig) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProviderConfig)
+	}
+	if !p.Enabled {
+		return nil
+	}
+	if p.ClientID == "" || p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "" {
+		return fmt.Errorf("%w: %s needs client_id, auth_url, token_url and user_info_url", ErrInvalidProviderConfig, p.Name)
+	}
+	return nil
+}
+
+// ProviderChange describes what SyncProviders did to one provider
+type ProviderChange struct {
+	Name   string
+	Action string   // created/updated/disabled/encrypted
+	Fields []string // 与配置不一致的字段，client_secret 只报告字段名
+}
+
+// Actions reported in ProviderChange
+const (
+	ProviderCreated   = "created"
+	ProviderUpdated   = "updated"
+	ProviderDisabled  = "disabled"
+	ProviderEncrypted = "encrypted"
+)
+
+// SyncProviders makes o_auth_providers match the configured providers. Missing
+// providers are created, drifted ones are updated and providers no longer configured
+// are disabled. With no providers configured the table is left to manual management,
+// except that plaintext client secrets are encrypted in place. Every change is
+// logged and recorded in the audit log.
+func SyncProviders(ctx context.Context, providers []ProviderConfig) ([]ProviderChange, error) {
+	var changes []ProviderChange
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.OAuthProvider
+		if err := tx.Find(&existing).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]*models.OAuthProvider, len(existing))
+		for i := range existing {
+			byName[existing[i].Name] = &existing[i]
+		}
+
+		configured := make(map[string]bool, len(providers))
+		for _, p := range providers {
+			configured[p.Name] = true
+
+			row, ok := byName[p.Name]
+			if !ok {
+				row = &models.OAuthProvider{Name: p.Name}
+			}
+			fields, err := applyProvider(row, p)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case !ok:
+				if err := tx.Create(row).Error; err != nil {
+					return err
+				}
+				// 数据库默认值为 true，禁用的提供商需要在创建后再写一次
+				if !p.Enabled {
+					if err := tx.Model(row).Update("enabled", false).Error; err != nil {
+						return err
+					}
+				}
+				changes = append(changes, ProviderChange{Name: p.Name, Action: ProviderCreated})
+			case len(fields) > 0:
+				if err := tx.Select("*").Omit("id", "created_at").Updates(row).Error; err != nil {
+					return err
+				}
+				changes = append(changes, ProviderChange{Name: p.Name, Action: ProviderUpdated, Fields: fields})
+			}
+		}
+
+		for _, row := range existing {
+			if configured[row.Name] {
+				continue
+			}
+			if len(providers) > 0 && row.Enabled {
+				if err := tx.Model(&row).Update("enabled", false).Error; err != nil {
+					return err
+				}
+				changes = append(changes, ProviderChange{Name: row.Name, Action: ProviderDisabled})
+			}
+			// 手工插入的明文密钥就地加密
+			if row.ClientSecret != nil && *row.ClientSecret != "" && !secretbox.IsSealed(*row.ClientSecret) {
+				sealed, err := secretbox.Seal(*row.ClientSecret)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&row).Update("client_secret", sealed).Error; err != nil {
+					return err
+				}
+				changes = append(changes, ProviderChange{Name: row.Name, Action: ProviderEncrypted})
+			}
+		}
+
+		for _, change := range changes {
+			err := audit.Record(ctx, tx, audit.Event{
+				Action:     audit.ActionOAuthProviderSynced,
+				TargetType: audit.TargetOAuthProvider,
+				TargetID:   change.Name,
+				After:      map[string]interface{}{"action": change.Action, "fields": change.Fields},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if len(change.Fields) > 0 {
+			logger.WarnContext(ctx, "OAuth provider drifted from configuration", "provider", change.Name, "action", change.Action, "fields", change.Fields)
+		} else {
+			logger.InfoContext(ctx, "OAuth provider synced", "provider", change.Name, "action", change.Action)
+		}
+	}
+	return changes, nil
+}
+
+// applyProvider copies p onto row and returns the names of the fields that differed
+func applyProvider(row *models.OAuthProvider, p ProviderConfig) ([]string, error) {
+	var fields []string
+	set := func(name string, dst *string, value string) {
+		if *dst != value {
+			fields = append(fields, name)
+			*dst = value
+		}
+	}
+
+	set("display_name", &row.DisplayName, p.DisplayName)
+	set("client_id", &row.ClientID, p.ClientID)
+	set("auth_url", &row.AuthURL, p.AuthURL)
+	set("token_url", &row.TokenURL, p.TokenURL)
+	set("user_info_url", &row.UserInfoURL, p.UserInfoURL)
+	set("scope", &row.Scope, p.Scope)
+	if row.Enabled != p.Enabled {
+		fields = append(fields, "enabled")
+		row.Enabled = p.Enabled
+	}
+	if row.SortOrder != p.SortOrder {
+		fields = append(fields, "sort_order")
+		row.SortOrder = p.SortOrder
+	}
+
+	// 密钥无法解密（如更换了加密密钥）或仍以明文保存时同样视为漂移，用配置中的值覆盖
+	current, err := ClientSecret(*row)
+	plaintext := row.ClientSecret != nil && *row.ClientSecret != "" && !secretbox.IsSealed(*row.ClientSecret)
+	if err != nil || current != p.ClientSecret || plaintext {
+		fields = append(fields, "client_secret")
+		row.ClientSecret = nil
+		if p.ClientSecret != "" {
+			sealed, err := secretbox.Seal(p.ClientSecret)
+			if err != nil {
+				return nil, err
+			}
+			row.ClientSecret = &sealed
+		}
+	}
+
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// ClientSecret returns the decrypted client secret of a provider, or "" when it has none
+func ClientSecret(provider models.OAuthProvider) (string, error) {
+	if provider.ClientSecret == nil {
+		return "", nil
+	}
+	return secretbox.Open(*provider.ClientSecret)
+}
+
+// ErrProviderMissing indicates that a required OAuth provider is missing or disabled
+var ErrProviderMissing = errors.New("required OAuth provider missing or disabled")
+
+// CheckProviders verifies that every required provider is enabled. With no required
+// providers, at least one enabled provider must exist, otherwise nobody can log in.
+func CheckProviders(ctx context.Context, required []string) error {
+	var enabled []string
+	if err := db.DB.WithContext(ctx).Model(&models.OAuthProvider{}).Where("enabled = ?", true).Pluck("name", &enabled).Error; err != nil {
+		return err
+	}
+	if len(required) == 0 && len(enabled) == 0 {
+		return fmt.Errorf("%w: no provider is enabled", ErrProviderMissing)
+	}
+
+	available := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		available[name] = true
+	}
+	for _, name := range required {
+		if !available[name] {
+			return fmt.Errorf("%w: %s", ErrProviderMissing, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package benefit
+
+import (
+	"giftredeem/internal/audit"
+	"giftredeem/internal/models"
+)
+
+// GetBenefitAuditEvents returns the audit events of one of the user's benefits
+func (s *BenefitService) GetBenefitAuditEvents(userID uint, benefitUUID string, query audit.Query) ([]models.AuditEvent, int64, error) {
+	benefit, err := getOwnedBenefit(userID, benefitUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 发布者只能查看自己福利相关的事件
+	query.BenefitID = &benefit.ID
+	return audit.List(query)
+}
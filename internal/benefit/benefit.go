@@ -1,14 +1,23 @@
 package benefit
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/codeformat"
 	"giftredeem/internal/db"
+	"giftredeem/internal/fraud"
+	"giftredeem/internal/metrics"
 	"giftredeem/internal/models"
-	"strings"
+	"giftredeem/internal/outbox"
+	"giftredeem/pkg/events"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -35,6 +44,19 @@ var (
 
 	// ErrAccountTooNew indicates the user's account is too new to claim this benefit
 	ErrAccountTooNew = errors.New("your account is too new to claim this benefit")
+
+	// ErrUserBannedByCreator indicates the creator banned the user from their benefits
+	ErrUserBannedByCreator = errors.New("you are not allowed to claim benefits from this creator")
+
+	// ErrPeriodLimitReached indicates the user has reached the claim limit for the current period
+	ErrPeriodLimitReached = errors.New("claim limit for the current period has been reached")
+)
+
+// Supported values for Benefit.LimitPeriod
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
 )
 
 // BenefitService handles benefit operations
@@ -47,45 +69,91 @@ func NewBenefitService() *BenefitService {
 
 // CreateBenefitInput represents the input for creating a new benefit
 type CreateBenefitInput struct {
-	Title            string                 `json:"title" binding:"required"`
-	Description      string                 `json:"description"`
-	Codes            []string               `json:"codes" binding:"required,min=1"`
-	ExpiresAt        *time.Time             `json:"expires_at"`
-	AllowedProviders []string               `json:"allowed_providers"`
-	MinAccountAge    int                    `json:"min_account_age"`
-	ClaimConditions  map[string]interface{} `json:"claim_conditions"`
+	Title               string                 `json:"title" binding:"required"`
+	Description         string                 `json:"description"`
+	Codes               []string               `json:"codes"`   // 默认档位的兑换码
+	Entries             []CodeEntry            `json:"entries"` // 带有独立元数据的兑换码
+	Tiers               []TierInput            `json:"tiers"`   // 分档位的兑换码，可与 Codes 同时使用
+	ExpiresAt           *time.Time             `json:"expires_at"`
+	AllowedProviders    []string               `json:"allowed_providers"`
+	MinAccountAge       int                    `json:"min_account_age"`
+	ClaimConditions     map[string]interface{} `json:"claim_conditions"`
+	PerUserQuota        int                    `json:"per_user_quota"`  // 默认 1
+	CodesPerClaim       int                    `json:"codes_per_claim"` // 默认 1
+	PeriodLimit         int                    `json:"period_limit"`
+	LimitPeriod         string                 `json:"limit_period"`
+	AllocationMode      string                 `json:"allocation_mode"` // sequential/weighted，默认 sequential
+	TierRules           []models.TierRule      `json:"tier_rules"`
+	CodeFormat          string                 `json:"code_format"`   // 空字符串表示不校验格式
+	CodePattern         string                 `json:"code_pattern"`  // CodeFormat 为 regex 时使用
+	CodeMetadata        models.CodeMetadata    `json:"code_metadata"` // 所有兑换码的默认元数据
+	Challenge           string                 `json:"challenge"`     // 空/pow/captcha
+	ChallengeDifficulty int                    `json:"challenge_difficulty"`
+	FraudThreshold      int                    `json:"fraud_threshold"` // 0 表示不启用风控
+	FraudAction         string                 `json:"fraud_action"`    // block/flag/review，默认 flag
+	Private             bool                   `json:"private"`         // 分享链接时不展示标题等详情
 }
 
 // CreateBenefit creates a new benefit with redemption codes
-func (s *BenefitService) CreateBenefit(userID uint, input CreateBenefitInput) (*models.Benefit, error) {
+func (s *BenefitService) CreateBenefit(ctx context.Context, userID uint, input CreateBenefitInput) (*models.Benefit, error) {
 	// Validate input
 	if input.Title == "" {
 		return nil, ErrInvalidInput
 	}
 
-	// Clean and validate codes
-	cleanedCodes := []string{}
-	for _, code := range input.Codes {
-		code = strings.TrimSpace(code)
-		if code != "" {
-			cleanedCodes = append(cleanedCodes, code)
-		}
+	// Normalize quota settings
+	if input.PerUserQuota <= 0 {
+		input.PerUserQuota = 1
+	}
+	if input.CodesPerClaim <= 0 {
+		input.CodesPerClaim = 1
+	}
+	if input.CodesPerClaim > input.PerUserQuota {
+		return nil, ErrInvalidInput
+	}
+	if input.PeriodLimit < 0 {
+		return nil, ErrInvalidInput
+	}
+	if input.PeriodLimit > 0 && !isValidPeriod(input.LimitPeriod) {
+		return nil, ErrInvalidInput
+	}
+	if input.PeriodLimit == 0 {
+		input.LimitPeriod = ""
+	}
+
+	if err := normalizeChallenge(&input); err != nil {
+		return nil, err
+	}
+	if err := normalizeFraud(&input); err != nil {
+		return nil, err
+	}
+
+	// Resolve the code format validator
+	validator, err := codeformat.Lookup(input.CodeFormat, input.CodePattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	// Clean, validate and deduplicate codes across all tiers
+	finalCodes, err := collectCodes(input, validator)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure we have at least one valid code
-	if len(cleanedCodes) == 0 {
+	if len(finalCodes) == 0 {
 		return nil, ErrInvalidInput
 	}
 
-	// Remove duplicates from codes
-	uniqueCodes := make(map[string]bool)
-	for _, code := range cleanedCodes {
-		uniqueCodes[code] = true
+	// Validate allocation settings
+	if input.AllocationMode == "" {
+		input.AllocationMode = AllocationSequential
 	}
-
-	finalCodes := []string{}
-	for code := range uniqueCodes {
-		finalCodes = append(finalCodes, code)
+	if input.AllocationMode != AllocationSequential && input.AllocationMode != AllocationWeighted {
+		return nil, ErrInvalidInput
+	}
+	if err := validateTierRules(input.TierRules, finalCodes); err != nil {
+		return nil, err
 	}
 
 	// Generate a UUID for the benefit
@@ -107,18 +175,31 @@ func (s *BenefitService) CreateBenefit(userID uint, input CreateBenefitInput) (*
 
 	// Create the benefit
 	benefit := models.Benefit{
-		UUID:             benefitUUID,
-		Title:            input.Title,
-		Description:      input.Description,
-		CreatorID:        userID,
-		TotalCount:       len(finalCodes),
-		ClaimedCount:     0,
-		CreatedAt:        time.Now(),
-		ExpiresAt:        expiresAt,
-		Status:           "active",
-		AllowedProviders: input.AllowedProviders,
-		MinAccountAge:    input.MinAccountAge,
-		ClaimConditions:  input.ClaimConditions,
+		UUID:                benefitUUID,
+		Title:               input.Title,
+		Description:         input.Description,
+		CreatorID:           userID,
+		TotalCount:          len(finalCodes),
+		ClaimedCount:        0,
+		CreatedAt:           time.Now(),
+		ExpiresAt:           expiresAt,
+		Status:              "active",
+		AllowedProviders:    input.AllowedProviders,
+		MinAccountAge:       input.MinAccountAge,
+		ClaimConditions:     input.ClaimConditions,
+		PerUserQuota:        input.PerUserQuota,
+		CodesPerClaim:       input.CodesPerClaim,
+		PeriodLimit:         input.PeriodLimit,
+		LimitPeriod:         input.LimitPeriod,
+		AllocationMode:      input.AllocationMode,
+		TierRules:           input.TierRules,
+		CodeFormat:          input.CodeFormat,
+		CodePattern:         input.CodePattern,
+		Challenge:           input.Challenge,
+		ChallengeDifficulty: input.ChallengeDifficulty,
+		FraudThreshold:      input.FraudThreshold,
+		FraudAction:         input.FraudAction,
+		Private:             input.Private,
 	}
 
 	if err := tx.Create(&benefit).Error; err != nil {
@@ -127,14 +208,11 @@ func (s *BenefitService) CreateBenefit(userID uint, input CreateBenefitInput) (*
 	}
 
 	// Create redemption codes
-	for _, code := range finalCodes {
-		redemptionCode := models.RedemptionCode{
-			BenefitID: benefit.ID,
-			Code:      code,
-			Status:    "available",
-			CreatedAt: time.Now(),
-			ClaimedAt: nil, // 显式设置为 nil，表示 NULL
-		}
+	for _, redemptionCode := range finalCodes {
+		redemptionCode.BenefitID = benefit.ID
+		redemptionCode.Status = "available"
+		redemptionCode.CreatedAt = time.Now()
+		redemptionCode.ClaimedAt = nil // 显式设置为 nil，表示 NULL
 
 		if err := tx.Create(&redemptionCode).Error; err != nil {
 			tx.Rollback()
@@ -142,6 +220,31 @@ func (s *BenefitService) CreateBenefit(userID uint, input CreateBenefitInput) (*
 		}
 	}
 
+	// Record the creation in the audit log
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionBenefitCreated,
+		TargetType: audit.TargetBenefit,
+		TargetID:   benefit.UUID,
+		BenefitID:  &benefit.ID,
+		After: map[string]interface{}{
+			"title":       benefit.Title,
+			"total_count": benefit.TotalCount,
+			"status":      benefit.Status,
+			"expires_at":  benefit.ExpiresAt,
+		},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Publish the domain event through the outbox
+	if err := outbox.Publish(tx, events.BenefitCreated, events.BenefitEvent{Benefit: benefitPayload(&benefit)}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
@@ -163,8 +266,60 @@ func (s *BenefitService) GetBenefitByUUID(uuid string) (*models.Benefit, error)
 	return &benefit, nil
 }
 
-// ClaimBenefit allows a user to claim a benefit
-func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider string, ipAddress, userAgent string) (*models.RedemptionCode, error) {
+// ClaimBenefit allows a user to claim a benefit. Depending on the benefit's
+// CodesPerClaim setting, a single claim may carry several redemption codes.
+// proof answers the benefit's challenge, if it requires one.
+func (s *BenefitService) ClaimBenefit(ctx context.Context, userID uint, benefitUUID string, provider string, proof ClaimProof, ipAddress, userAgent string) (*models.Claim, error) {
+	claim, err := s.claimBenefit(ctx, userID, benefitUUID, provider, proof, ipAddress, userAgent)
+	metrics.ObserveClaim(ClaimOutcome(claim, err))
+	return claim, err
+}
+
+// claimErrors names the errors a claim can fail with, for metrics
+var claimErrors = []struct {
+	name string
+	err  error
+}{
+	{"ErrInvalidInput", ErrInvalidInput},
+	{"ErrNotFound", ErrNotFound},
+	{"ErrAlreadyClaimed", ErrAlreadyClaimed},
+	{"ErrNoCodeAvailable", ErrNoCodeAvailable},
+	{"ErrBenefitExpired", ErrBenefitExpired},
+	{"ErrBenefitPaused", ErrBenefitPaused},
+	{"ErrProviderNotAllowed", ErrProviderNotAllowed},
+	{"ErrAccountTooNew", ErrAccountTooNew},
+	{"ErrUserBannedByCreator", ErrUserBannedByCreator},
+	{"ErrPeriodLimitReached", ErrPeriodLimitReached},
+	{"ErrChallengeRequired", ErrChallengeRequired},
+	{"ErrChallengeFailed", ErrChallengeFailed},
+	{"ErrClaimBlocked", ErrClaimBlocked},
+}
+
+// ClaimOutcome describes the result of a claim attempt: "success", "pending_review"
+// for claims held for the creator's review, the name of the error for known
+// failures and "error" for anything else
+func ClaimOutcome(claim *models.Claim, err error) string {
+	if err == nil {
+		if claim != nil && claim.ReviewStatus == ReviewPending {
+			return "pending_review"
+		}
+		return "success"
+	}
+	for _, known := range claimErrors {
+		if errors.Is(err, known.err) {
+			return known.name
+		}
+	}
+	return "error"
+}
+
+func (s *BenefitService) claimBenefit(ctx context.Context, userID uint, benefitUUID string, provider string, proof ClaimProof, ipAddress, userAgent string) (*models.Claim, error) {
+	// Verify the challenge before locking the benefit
+	usedChallenge, err := verifyClaimProof(ctx, benefitUUID, proof, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	// Start a transaction
 	tx := db.DB.Begin()
 	defer func() {
@@ -173,9 +328,9 @@ func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider
 		}
 	}()
 
-	// Get the benefit
+	// Get the benefit, locking the row so concurrent claims are serialized per benefit
 	var benefit models.Benefit
-	if err := tx.Where("uuid = ?", benefitUUID).First(&benefit).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", benefitUUID).First(&benefit).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
@@ -194,6 +349,25 @@ func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider
 		return nil, ErrNotFound
 	}
 
+	// Proof-of-work tokens are single use
+	if usedChallenge != nil {
+		if err := markChallengeUsed(tx, usedChallenge); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Check whether the creator banned this user
+	var bans int64
+	if err := tx.Model(&models.CreatorBan{}).Where("creator_id = ? AND user_id = ?", benefit.CreatorID, userID).Count(&bans).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if bans > 0 {
+		tx.Rollback()
+		return nil, ErrUserBannedByCreator
+	}
+
 	// Check provider restrictions
 	if len(benefit.AllowedProviders) > 0 {
 		allowed := false
@@ -210,14 +384,14 @@ func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider
 		}
 	}
 
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Check account age restriction
 	if benefit.MinAccountAge > 0 {
-		var user models.User
-		if err := tx.First(&user, userID).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-
 		accountAge := int(time.Since(user.CreatedAt).Hours() / 24)
 		if accountAge < benefit.MinAccountAge {
 			tx.Rollback()
@@ -225,46 +399,124 @@ func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider
 		}
 	}
 
-	// Check if user has already claimed this benefit
-	var existingClaim models.Claim
-	err := tx.Where("user_id = ? AND benefit_id = ?", userID, benefit.ID).First(&existingClaim).Error
-	if err == nil {
+	// Check the user's total quota for this benefit
+	received, err := countUserCodes(tx, userID, benefit.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	remaining := quotaOf(&benefit) - received
+	if remaining <= 0 {
 		tx.Rollback()
 		return nil, ErrAlreadyClaimed
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+	}
+
+	// Check the per-period limit
+	if benefit.PeriodLimit > 0 {
+		periodClaims, err := countPeriodClaims(tx, userID, &benefit, time.Now())
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if periodClaims >= benefit.PeriodLimit {
+			tx.Rollback()
+			return nil, ErrPeriodLimitReached
+		}
+	}
+
+	// Score the attempt and apply the benefit's fraud threshold
+	now := time.Now()
+	assessment, err := fraud.Score(tx, fraud.Attempt{
+		BenefitID:        benefit.ID,
+		UserID:           userID,
+		IP:               ipAddress,
+		UserAgent:        userAgent,
+		AccountCreatedAt: user.CreatedAt,
+		Now:              now,
+	})
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	// Get an available redemption code
-	var code models.RedemptionCode
-	if err := tx.Where("benefit_id = ? AND status = ?", benefit.ID, "available").First(&code).Error; err != nil {
+	reviewStatus, err := fraudOutcome(&benefit, assessment)
+	if errors.Is(err, ErrClaimBlocked) {
 		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrNoCodeAvailable
-		}
+		audit.RecordBestEffort(ctx, audit.Event{
+			ActorID:    &userID,
+			Action:     audit.ActionClaimBlocked,
+			TargetType: audit.TargetBenefit,
+			TargetID:   benefit.UUID,
+			BenefitID:  &benefit.ID,
+			After: map[string]interface{}{
+				"risk_score":   assessment.Score,
+				"risk_reasons": assessment.Reasons,
+				"threshold":    benefit.FraudThreshold,
+			},
+		})
 		return nil, err
 	}
 
-	// Update the code status
-	code.Status = "claimed"
-	code.ClaimedBy = &userID
-	now := time.Now()
-	code.ClaimedAt = &now
-	if err := tx.Save(&code).Error; err != nil {
+	// Determine how many codes this claim carries
+	want := benefit.CodesPerClaim
+	if want <= 0 {
+		want = 1
+	}
+	if want > remaining {
+		want = remaining
+	}
+
+	// Count earlier claims to determine this claim's sequence number
+	seq, err := nextClaimSeq(tx, benefit.ID)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	// Allocate redemption codes according to the benefit's tier settings
+	var codes []models.RedemptionCode
+	for len(codes) < want {
+		code, err := allocateCode(tx, &benefit, seq)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		// Update the code status
+		code.Status = "claimed"
+		code.ClaimedBy = &userID
+		code.ClaimedAt = &now
+		if err := tx.Save(code).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		codes = append(codes, *code)
+	}
+
+	if len(codes) == 0 {
+		tx.Rollback()
+		return nil, ErrNoCodeAvailable
+	}
+
 	// Create claim record
 	claim := models.Claim{
 		UserID:        userID,
 		BenefitID:     benefit.ID,
-		CodeID:        code.ID,
+		CodeID:        codes[0].ID,
 		OAuthProvider: provider,
-		ClaimedAt:     time.Now(),
+		ClaimedAt:     now,
 		IPAddress:     ipAddress,
+		Subnet:        fraud.SubnetOf(ipAddress),
 		UserAgent:     userAgent,
+		RiskScore:     assessment.Score,
+		RiskReasons:   assessment.Reasons,
+		ReviewStatus:  reviewStatus,
 	}
 
 	if err := tx.Create(&claim).Error; err != nil {
@@ -272,19 +524,107 @@ func (s *BenefitService) ClaimBenefit(userID uint, benefitUUID string, provider
 		return nil, err
 	}
 
+	// Create claim items, one per code
+	for _, code := range codes {
+		item := models.ClaimItem{
+			ClaimID:   claim.ID,
+			CodeID:    code.ID,
+			CreatedAt: now,
+		}
+
+		if err := tx.Create(&item).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		item.RedemptionCode = code
+		claim.Items = append(claim.Items, item)
+	}
+
 	// Update claimed count
-	benefit.ClaimedCount++
+	benefit.ClaimedCount += len(codes)
 	if err := tx.Save(&benefit).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	// Record the claim in the audit log
+	codeIDs := make([]uint, len(codes))
+	for i, code := range codes {
+		codeIDs[i] = code.ID
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionClaimCreated,
+		TargetType: audit.TargetClaim,
+		TargetID:   strconv.FormatUint(uint64(claim.ID), 10),
+		BenefitID:  &benefit.ID,
+		After: map[string]interface{}{
+			"code_ids":      codeIDs,
+			"provider":      provider,
+			"claimed_count": benefit.ClaimedCount,
+			"risk_score":    claim.RiskScore,
+			"review_status": claim.ReviewStatus,
+		},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Publish the domain events through the outbox
+	claimEvent := events.BenefitEvent{
+		Benefit: benefitPayload(&benefit),
+		Claim:   claimPayload(&claim),
+	}
+	if err := outbox.Publish(tx, events.ClaimCreated, claimEvent); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Announce depletion when the last code has been handed out
+	if err := publishIfDepleted(tx, &benefit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
 
-	return &code, nil
+	claim.Benefit = benefit
+	claim.RedemptionCode = codes[0]
+	return &claim, nil
+}
+
+// Quota describes what is left of a user's limits on a benefit
+type Quota struct {
+	Remaining       int        // 还能领取的兑换码数量
+	PeriodRemaining int        // 当前周期内还能领取的次数，福利没有周期限制时为 -1
+	PeriodResetsAt  *time.Time // 下一个周期开始的时间，福利没有周期限制时为空
+}
+
+// GetRemainingQuota returns how many more codes the user may receive from the benefit,
+// and how many more claims the per-period limit allows before it resets
+func (s *BenefitService) GetRemainingQuota(userID uint, benefit *models.Benefit) (*Quota, error) {
+	received, err := countUserCodes(db.DB, userID, benefit.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &Quota{Remaining: max(quotaOf(benefit)-received, 0), PeriodRemaining: -1}
+	if benefit.PeriodLimit > 0 {
+		now := time.Now()
+		periodClaims, err := countPeriodClaims(db.DB, userID, benefit, now)
+		if err != nil {
+			return nil, err
+		}
+		resetsAt := periodEnd(benefit.LimitPeriod, now)
+		quota.PeriodRemaining = max(benefit.PeriodLimit-periodClaims, 0)
+		quota.PeriodResetsAt = &resetsAt
+	}
+	return quota, nil
 }
 
 // GetUserBenefits retrieves benefits created by a user
@@ -297,16 +637,17 @@ func (s *BenefitService) GetUserBenefits(userID uint) ([]models.Benefit, error)
 // GetUserClaims retrieves benefits claimed by a user
 func (s *BenefitService) GetUserClaims(userID uint) ([]models.Claim, error) {
 	var claims []models.Claim
-	err := db.DB.Where("user_id = ?", userID).
+	err := db.DB.Where("user_id = ? AND status = ?", userID, "active").
 		Preload("Benefit").
 		Preload("RedemptionCode").
+		Preload("Items.RedemptionCode").
 		Order("claimed_at DESC").
 		Find(&claims).Error
 	return claims, err
 }
 
 // UpdateBenefitStatus updates the status of a benefit
-func (s *BenefitService) UpdateBenefitStatus(userID uint, benefitUUID, status string) error {
+func (s *BenefitService) UpdateBenefitStatus(ctx context.Context, userID uint, benefitUUID, status string) error {
 	if status != "active" && status != "paused" && status != "expired" && status != "deleted" {
 		return ErrInvalidInput
 	}
@@ -320,33 +661,165 @@ func (s *BenefitService) UpdateBenefitStatus(userID uint, benefitUUID, status st
 		return err
 	}
 
-	// Update status
-	benefit.Status = status
-	return db.DB.Save(&benefit).Error
+	// Update status together with its audit record
+	previous := benefit.Status
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&benefit).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		err := audit.Record(ctx, tx, audit.Event{
+			ActorID:    &userID,
+			Action:     audit.ActionBenefitStatusChanged,
+			TargetType: audit.TargetBenefit,
+			TargetID:   benefit.UUID,
+			BenefitID:  &benefit.ID,
+			Before:     map[string]interface{}{"status": previous},
+			After:      map[string]interface{}{"status": status},
+		})
+		if err != nil {
+			return err
+		}
+
+		benefit.Status = status
+		return outbox.Publish(tx, events.BenefitStatusChanged, events.BenefitEvent{
+			Benefit:        benefitPayload(&benefit),
+			PreviousStatus: previous,
+		})
+	})
 }
 
 // GetBenefitClaims retrieves claims for a specific benefit
-func (s *BenefitService) GetBenefitClaims(userID uint, benefitUUID string) ([]models.Claim, error) {
+func (s *BenefitService) GetBenefitClaims(ctx context.Context, userID uint, benefitUUID string) ([]models.Claim, error) {
 	// Get the benefit
-	var benefit models.Benefit
-	if err := db.DB.Where("uuid = ? AND creator_id = ?", benefitUUID, userID).First(&benefit).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrNotFound
-		}
+	benefit, err := getOwnedBenefit(userID, benefitUUID)
+	if err != nil {
 		return nil, err
 	}
 
 	// Get claims
 	var claims []models.Claim
-	err := db.DB.Where("benefit_id = ?", benefit.ID).
+	err = db.DB.Where("benefit_id = ?", benefit.ID).
 		Preload("User").
+		Preload("RedemptionCode").
+		Preload("Items.RedemptionCode").
 		Order("claimed_at DESC").
 		Find(&claims).Error
+	if err != nil {
+		return nil, err
+	}
 
-	return claims, err
+	audit.RecordBestEffort(ctx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionBenefitClaimsViewed,
+		TargetType: audit.TargetBenefit,
+		TargetID:   benefit.UUID,
+		BenefitID:  &benefit.ID,
+	})
+
+	return claims, nil
 }
 
 // GetClaimURL generates the claim URL for a benefit
 func (s *BenefitService) GetClaimURL(baseURL, benefitUUID string) string {
 	return baseURL + "/claim/" + benefitUUID
 }
+
+// benefitPayload describes a benefit in domain event payloads
+func benefitPayload(benefit *models.Benefit) events.BenefitPayload {
+	return events.BenefitPayload{
+		ID:           benefit.ID,
+		UUID:         benefit.UUID,
+		Title:        benefit.Title,
+		Status:       benefit.Status,
+		CreatorID:    benefit.CreatorID,
+		TotalCount:   benefit.TotalCount,
+		ClaimedCount: benefit.ClaimedCount,
+		ExpiresAt:    benefit.ExpiresAt,
+	}
+}
+
+// publishIfDepleted publishes benefit.depleted when the benefit has no available codes left
+func publishIfDepleted(tx *gorm.DB, benefit *models.Benefit) error {
+	var availableLeft int64
+	if err := availableCodes(tx, benefit.ID).Count(&availableLeft).Error; err != nil {
+		return err
+	}
+	if availableLeft > 0 {
+		return nil
+	}
+	return outbox.Publish(tx, events.BenefitDepleted, events.BenefitEvent{Benefit: benefitPayload(benefit)})
+}
+
+// getOwnedBenefit retrieves a benefit by UUID, making sure it belongs to the user
+func getOwnedBenefit(userID uint, benefitUUID string) (*models.Benefit, error) {
+	var benefit models.Benefit
+	if err := db.DB.Where("uuid = ? AND creator_id = ?", benefitUUID, userID).First(&benefit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &benefit, nil
+}
+
+// countUserCodes counts the redemption codes a user has received from a benefit
+func countUserCodes(tx *gorm.DB, userID, benefitID uint) (int, error) {
+	var count int64
+	err := tx.Model(&models.ClaimItem{}).
+		Joins("JOIN claims ON claims.id = claim_items.claim_id").
+		Where("claims.user_id = ? AND claims.benefit_id = ? AND claims.status = ? AND claim_items.replaced_at IS NULL", userID, benefitID, "active").
+		Count(&count).Error
+	return int(count), err
+}
+
+// quotaOf returns the effective per-user quota of a benefit
+func quotaOf(benefit *models.Benefit) int {
+	if benefit.PerUserQuota <= 0 {
+		return 1
+	}
+	return benefit.PerUserQuota
+}
+
+// isValidPeriod reports whether period is a supported LimitPeriod value
+func isValidPeriod(period string) bool {
+	return period == PeriodDay || period == PeriodWeek || period == PeriodMonth
+}
+
+// periodStart returns the beginning of the period containing t
+func periodStart(period string, t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch period {
+	case PeriodWeek:
+		// 以周一作为一周的开始
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// periodEnd returns the start of the period following the one containing t
+func periodEnd(period string, t time.Time) time.Time {
+	start := periodStart(period, t)
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// countPeriodClaims counts the user's active claims on the benefit in the period containing now
+func countPeriodClaims(tx *gorm.DB, userID uint, benefit *models.Benefit, now time.Time) (int, error) {
+	var count int64
+	err := tx.Model(&models.Claim{}).
+		Where("user_id = ? AND benefit_id = ? AND status = ? AND claimed_at >= ?", userID, benefit.ID, "active", periodStart(benefit.LimitPeriod, now)).
+		Count(&count).Error
+	return int(count), err
+}
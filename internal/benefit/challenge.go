@@ -0,0 +1,149 @@
+package benefit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"giftredeem/internal/challenge"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrChallengeRequired indicates the benefit requires a challenge that was not answered
+	ErrChallengeRequired = errors.New("a challenge must be completed before claiming this benefit")
+
+	// ErrChallengeFailed indicates a wrong, expired or reused challenge answer
+	ErrChallengeFailed = errors.New("challenge verification failed")
+)
+
+// ClaimProof carries the answer to a benefit's claim challenge
+type ClaimProof struct {
+	ChallengeToken    string `json:"challenge_token"`
+	ChallengeSolution string `json:"challenge_solution"`
+	CaptchaResponse   string `json:"captcha_response"`
+}
+
+// ChallengeInfo tells the client what it has to solve before claiming
+type ChallengeInfo struct {
+	Type    string         `json:"type"` // none/pow/captcha
+	PoW     *challenge.PoW `json:"pow,omitempty"`
+	SiteKey string         `json:"site_key,omitempty"`
+}
+
+// IssueChallenge returns the challenge to solve before claiming the benefit
+func (s *BenefitService) IssueChallenge(benefitUUID string) (*ChallengeInfo, error) {
+	benefit, err := s.GetBenefitByUUID(benefitUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch benefit.Challenge {
+	case challenge.TypePoW:
+		cleanupUsedChallenges()
+		pow, err := challenge.IssuePoW(benefit.UUID, benefit.ChallengeDifficulty)
+		if err != nil {
+			return nil, err
+		}
+		return &ChallengeInfo{Type: challenge.TypePoW, PoW: pow}, nil
+	case challenge.TypeCaptcha:
+		return &ChallengeInfo{Type: challenge.TypeCaptcha, SiteKey: challenge.CaptchaSiteKey()}, nil
+	}
+
+	return &ChallengeInfo{Type: "none"}, nil
+}
+
+// normalizeChallenge validates the challenge settings of a new benefit
+func normalizeChallenge(input *CreateBenefitInput) error {
+	switch input.Challenge {
+	case challenge.TypeNone:
+		input.ChallengeDifficulty = 0
+	case challenge.TypePoW:
+		if input.ChallengeDifficulty == 0 {
+			input.ChallengeDifficulty = challenge.DefaultDifficulty
+		}
+		if input.ChallengeDifficulty < challenge.MinDifficulty || input.ChallengeDifficulty > challenge.MaxDifficulty {
+			return ErrInvalidInput
+		}
+	case challenge.TypeCaptcha:
+		if !challenge.CaptchaEnabled() {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, challenge.ErrCaptchaUnavailable)
+		}
+		input.ChallengeDifficulty = 0
+	default:
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// verifyClaimProof checks the proof against the benefit's challenge before the claim
+// transaction starts, so slow CAPTCHA lookups never hold the benefit lock. For
+// proof-of-work it returns the token to mark as used inside the claim transaction.
+func verifyClaimProof(ctx context.Context, benefitUUID string, proof ClaimProof, ipAddress string) (*models.UsedChallenge, error) {
+	var benefit models.Benefit
+	if err := db.DB.Select("uuid", "challenge").Where("uuid = ?", benefitUUID).First(&benefit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	switch benefit.Challenge {
+	case challenge.TypePoW:
+		if proof.ChallengeToken == "" {
+			return nil, ErrChallengeRequired
+		}
+		expiresAt, err := challenge.VerifyPoW(benefit.UUID, proof.ChallengeToken, proof.ChallengeSolution)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrChallengeFailed, err)
+		}
+		return &models.UsedChallenge{Key: challenge.TokenKey(proof.ChallengeToken), ExpiresAt: expiresAt}, nil
+	case challenge.TypeCaptcha:
+		if proof.CaptchaResponse == "" {
+			return nil, ErrChallengeRequired
+		}
+		if err := challenge.VerifyCaptcha(ctx, proof.CaptchaResponse, ipAddress); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrChallengeFailed, err)
+		}
+	}
+
+	return nil, nil
+}
+
+// markChallengeUsed records a proof-of-work token as redeemed, failing if it already was
+func markChallengeUsed(tx *gorm.DB, used *models.UsedChallenge) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(used)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: challenge already used", ErrChallengeFailed)
+	}
+	return nil
+}
+
+var (
+	cleanupMu   sync.Mutex
+	lastCleanup time.Time
+)
+
+// cleanupUsedChallenges deletes expired tokens, at most once a minute
+func cleanupUsedChallenges() {
+	cleanupMu.Lock()
+	now := time.Now()
+	if now.Sub(lastCleanup) < time.Minute {
+		cleanupMu.Unlock()
+		return
+	}
+	lastCleanup = now
+	cleanupMu.Unlock()
+
+	if err := db.DB.Where("expires_at < ?", now).Delete(&models.UsedChallenge{}).Error; err != nil {
+		logger.Error("failed to delete expired challenges", "error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package benefit
+
+import (
+	"fmt"
+	"giftredeem/internal/codeformat"
+	"giftredeem/internal/models"
+	"strings"
+)
+
+// CodeEntry represents a single code imported together with its own metadata
+type CodeEntry struct {
+	Code     string              `json:"code" binding:"required"`
+	Tier     string              `json:"tier"` // 必须是 Tiers 中已声明的档位，空字符串表示默认档位
+	Metadata models.CodeMetadata `json:"metadata"`
+}
+
+// LineError describes a single malformed code found during import
+type LineError struct {
+	Source string `json:"source"` // codes / entries / tiers[i].codes
+	Line   int    `json:"line"`   // 从 1 开始的行号
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// ImportError is returned by CreateBenefit when some codes do not match the benefit's code format
+type ImportError struct {
+	Lines []LineError
+}
+
+// Error implements the error interface
+func (e *ImportError) Error() string {
+	parts := make([]string, 0, len(e.Lines))
+	for _, l := range e.Lines {
+		parts = append(parts, fmt.Sprintf("%s line %d: %s", l.Source, l.Line, l.Reason))
+	}
+	return "malformed codes: " + strings.Join(parts, "; ")
+}
+
+// Is lets errors.Is(err, ErrInvalidInput) match import errors
+func (e *ImportError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// collectCodes cleans, validates and deduplicates the codes of all sources, keeping the first occurrence.
+// Codes that fail the validator are reported with their source and line number.
+func collectCodes(input CreateBenefitInput, validator codeformat.Validator) ([]models.RedemptionCode, error) {
+	seen := make(map[string]bool)
+	codes := []models.RedemptionCode{}
+	importErr := &ImportError{}
+
+	type tierInfo struct {
+		weight   int
+		value    float64
+		metadata models.CodeMetadata
+	}
+	tiers := map[string]tierInfo{"": {weight: 1, metadata: input.CodeMetadata}}
+	for _, tier := range input.Tiers {
+		info := tierInfo{weight: tier.Weight, value: tier.Value, metadata: input.CodeMetadata}
+		if info.weight <= 0 {
+			info.weight = 1
+		}
+		if tier.Metadata != nil {
+			info.metadata = tier.Metadata.Merge(input.CodeMetadata)
+		}
+		tiers[strings.TrimSpace(tier.Name)] = info
+	}
+
+	add := func(source string, line int, code, tier string, metadata *models.CodeMetadata) {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			return
+		}
+
+		info, ok := tiers[tier]
+		if !ok {
+			importErr.Lines = append(importErr.Lines, LineError{Source: source, Line: line, Code: code, Reason: "unknown tier " + tier})
+			return
+		}
+
+		if validator != nil {
+			if err := validator.Validate(code); err != nil {
+				importErr.Lines = append(importErr.Lines, LineError{Source: source, Line: line, Code: code, Reason: err.Error()})
+				return
+			}
+		}
+
+		meta := info.metadata
+		if metadata != nil {
+			meta = metadata.Merge(info.metadata)
+		}
+
+		seen[code] = true
+		codes = append(codes, models.RedemptionCode{
+			Code:       code,
+			Tier:       tier,
+			TierWeight: info.weight,
+			TierValue:  info.value,
+			Metadata:   meta,
+		})
+	}
+
+	for i, code := range input.Codes {
+		add("codes", i+1, code, "", nil)
+	}
+	for i, entry := range input.Entries {
+		add("entries", i+1, entry.Code, strings.TrimSpace(entry.Tier), &entry.Metadata)
+	}
+	for t, tier := range input.Tiers {
+		for i, code := range tier.Codes {
+			add(fmt.Sprintf("tiers[%d].codes", t), i+1, code, strings.TrimSpace(tier.Name), nil)
+		}
+	}
+
+	if len(importErr.Lines) > 0 {
+		return nil, importErr
+	}
+	return codes, nil
+}
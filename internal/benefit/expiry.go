@@ -0,0 +1,81 @@
+package benefit
+
+import (
+	"context"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"giftredeem/internal/outbox"
+	"giftredeem/pkg/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var logger = logging.For("benefit")
+
+// ExpiryWatcher publishes a benefit.expiring event once for every active benefit
+// that still has codes left and expires within the configured window.
+type ExpiryWatcher struct {
+	window    time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+// NewExpiryWatcher creates a watcher that warns about benefits expiring within window
+func NewExpiryWatcher(window time.Duration) *ExpiryWatcher {
+	return &ExpiryWatcher{
+		window:    window,
+		interval:  10 * time.Minute,
+		batchSize: 100,
+	}
+}
+
+// Run scans for expiring benefits until ctx is cancelled
+func (w *ExpiryWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.scan(); err != nil {
+			logger.Error("failed to scan for expiring benefits", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan marks due benefits as noticed and publishes their events in one transaction,
+// so every benefit is announced exactly once even with several replicas running.
+func (w *ExpiryWatcher) scan() error {
+	now := time.Now()
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var benefits []models.Benefit
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expiring_notice_at IS NULL", "active").
+			Where("expires_at > ? AND expires_at <= ?", now, now.Add(w.window)).
+			Where("claimed_count < total_count").
+			Limit(w.batchSize).
+			Find(&benefits).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range benefits {
+			if err := tx.Model(&benefits[i]).Update("expiring_notice_at", now).Error; err != nil {
+				return err
+			}
+			if err := outbox.Publish(tx, events.BenefitExpiring, events.BenefitEvent{Benefit: benefitPayload(&benefits[i])}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,280 @@
+package benefit
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/internal/outbox"
+	"giftredeem/pkg/events"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values for ClaimItem.Feedback
+const (
+	FeedbackRedeemed    = "redeemed"
+	FeedbackInvalid     = "invalid"
+	FeedbackAlreadyUsed = "already_used"
+)
+
+var (
+	// ErrClaimNotFound indicates the claim or the claimed code does not exist
+	ErrClaimNotFound = errors.New("claim not found")
+
+	// ErrReplacementNotAllowed indicates the code was not reported as already used or has been replaced
+	ErrReplacementNotAllowed = errors.New("only codes reported as already used can be replaced")
+)
+
+// FeedbackSummary aggregates claimer feedback for a benefit
+type FeedbackSummary struct {
+	Redeemed    int `json:"redeemed"`
+	Invalid     int `json:"invalid"`
+	AlreadyUsed int `json:"already_used"`
+	NoFeedback  int `json:"no_feedback"`
+	Replaced    int `json:"replaced"`
+}
+
+// SubmitFeedback records the claimer's report on a claimed code. itemID may be
+// zero when the claim carries a single code.
+func (s *BenefitService) SubmitFeedback(userID, claimID, itemID uint, feedback, note string) (*models.ClaimItem, error) {
+	if feedback != FeedbackRedeemed && feedback != FeedbackInvalid && feedback != FeedbackAlreadyUsed {
+		return nil, ErrInvalidInput
+	}
+
+	var claim models.Claim
+	if err := db.DB.Where("id = ? AND user_id = ? AND status = ? AND review_status <> ?", claimID, userID, "active", ReviewPending).Preload("Items").First(&claim).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClaimNotFound
+		}
+		return nil, err
+	}
+
+	item, err := findClaimItem(&claim, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已补发的兑换码不再接受反馈
+	if item.ReplacedAt != nil {
+		return nil, ErrReplacementNotAllowed
+	}
+
+	now := time.Now()
+	item.Feedback = feedback
+	item.FeedbackNote = strings.TrimSpace(note)
+	item.FeedbackAt = &now
+	if err := db.DB.Model(item).Updates(map[string]interface{}{
+		"feedback":      item.Feedback,
+		"feedback_note": item.FeedbackNote,
+		"feedback_at":   item.FeedbackAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// GetFeedbackSummary aggregates the feedback of all codes claimed from a benefit
+func (s *BenefitService) GetFeedbackSummary(userID uint, benefitUUID string) (*FeedbackSummary, error) {
+	benefit, err := getOwnedBenefit(userID, benefitUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Feedback string
+		Replaced bool
+		Count    int
+	}
+	err = db.DB.Model(&models.ClaimItem{}).
+		Select("claim_items.feedback, claim_items.replaced_at IS NOT NULL AS replaced, COUNT(*) AS count").
+		Joins("JOIN claims ON claims.id = claim_items.claim_id").
+		Where("claims.benefit_id = ?", benefit.ID).
+		Group("claim_items.feedback, replaced").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &FeedbackSummary{}
+	for _, row := range rows {
+		if row.Replaced {
+			summary.Replaced += row.Count
+		}
+		switch row.Feedback {
+		case FeedbackRedeemed:
+			summary.Redeemed += row.Count
+		case FeedbackInvalid:
+			summary.Invalid += row.Count
+		case FeedbackAlreadyUsed:
+			summary.AlreadyUsed += row.Count
+		default:
+			summary.NoFeedback += row.Count
+		}
+	}
+
+	return summary, nil
+}
+
+// ReplaceCode issues a new code from the same benefit for a code the claimer
+// reported as already used. Calling it is the creator's validation of the report:
+// the old code is marked invalid and a replacement, preferably of the same tier,
+// is added to the claim.
+func (s *BenefitService) ReplaceCode(ctx context.Context, userID uint, benefitUUID string, claimID, itemID uint) (*models.ClaimItem, error) {
+	tx := db.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var benefit models.Benefit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ? AND creator_id = ?", benefitUUID, userID).First(&benefit).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var claim models.Claim
+	if err := tx.Where("id = ? AND benefit_id = ? AND status = ?", claimID, benefit.ID, "active").Preload("Items.RedemptionCode").First(&claim).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClaimNotFound
+		}
+		return nil, err
+	}
+
+	item, err := findClaimItem(&claim, itemID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if item.Feedback != FeedbackAlreadyUsed || item.ReplacedAt != nil {
+		tx.Rollback()
+		return nil, ErrReplacementNotAllowed
+	}
+
+	// Prefer a code of the same tier, falling back to the regular allocation
+	code, err := firstAvailable(availableCodes(tx, benefit.ID).Where("tier = ?", item.RedemptionCode.Tier))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 不套用档位规则，但仍避开为之后的领取者保留的档位
+		var seq int
+		seq, err = nextClaimSeq(tx, benefit.ID)
+		if err == nil {
+			code, err = allocateUnreserved(tx, &benefit, seq)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoCodeAvailable
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	code.Status = "claimed"
+	code.ClaimedBy = &claim.UserID
+	code.ClaimedAt = &now
+	if err := tx.Save(code).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Mark the reported code as invalid
+	if err := tx.Model(&models.RedemptionCode{}).Where("id = ?", item.CodeID).Update("status", "invalid").Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	replacement := models.ClaimItem{
+		ClaimID:   claim.ID,
+		CodeID:    code.ID,
+		CreatedAt: now,
+	}
+	if err := tx.Create(&replacement).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Model(&models.ClaimItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+		"replaced_by_id": replacement.ID,
+		"replaced_at":    now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	benefit.ClaimedCount++
+	if err := tx.Save(&benefit).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionCodeReplaced,
+		TargetType: audit.TargetClaim,
+		TargetID:   strconv.FormatUint(uint64(claim.ID), 10),
+		BenefitID:  &benefit.ID,
+		Before:     map[string]interface{}{"code_id": item.CodeID, "feedback": item.Feedback},
+		After:      map[string]interface{}{"code_id": code.ID},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Publish the domain events through the outbox
+	err = outbox.Publish(tx, events.ClaimCodeReplaced, events.BenefitEvent{
+		Benefit: benefitPayload(&benefit),
+		Claim:   claimPayload(&claim),
+		Replacement: &events.ReplacementPayload{
+			ItemID:            item.ID,
+			ReplacementItemID: replacement.ID,
+			Tier:              code.Tier,
+		},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := publishIfDepleted(tx, &benefit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	replacement.RedemptionCode = *code
+	return &replacement, nil
+}
+
+// findClaimItem selects an item of the claim, defaulting to the only item when itemID is zero
+func findClaimItem(claim *models.Claim, itemID uint) (*models.ClaimItem, error) {
+	if itemID == 0 {
+		if len(claim.Items) != 1 {
+			return nil, ErrInvalidInput
+		}
+		return &claim.Items[0], nil
+	}
+
+	for i := range claim.Items {
+		if claim.Items[i].ID == itemID {
+			return &claim.Items[i], nil
+		}
+	}
+
+	return nil, ErrClaimNotFound
+}
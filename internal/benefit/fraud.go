@@ -0,0 +1,212 @@
+package benefit
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/db"
+	"giftredeem/internal/fraud"
+	"giftredeem/internal/models"
+	"giftredeem/internal/outbox"
+	"giftredeem/pkg/events"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values for Benefit.FraudAction
+const (
+	// FraudActionBlock rejects claims scoring at or above the threshold
+	FraudActionBlock = "block"
+
+	// FraudActionFlag lets the claim through but marks it for the creator
+	FraudActionFlag = "flag"
+
+	// FraudActionReview holds the codes back until the creator approves the claim
+	FraudActionReview = "review"
+)
+
+// Values of Claim.ReviewStatus
+const (
+	ReviewFlagged  = "flagged"
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Supported values for ReviewClaimInput.Decision
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+var (
+	// ErrClaimBlocked indicates the claim attempt scored above the benefit's fraud threshold
+	ErrClaimBlocked = errors.New("this claim was blocked by the benefit's fraud protection")
+
+	// ErrClaimNotUnderReview indicates the claim is neither flagged nor awaiting review
+	ErrClaimNotUnderReview = errors.New("claim is not awaiting review")
+)
+
+// ReviewClaimInput represents the creator's decision on a flagged or held claim
+type ReviewClaimInput struct {
+	Decision string `json:"decision" binding:"required"` // approve/reject
+	Reason   string `json:"reason"`
+}
+
+// normalizeFraud validates the fraud protection settings of a new benefit
+func normalizeFraud(input *CreateBenefitInput) error {
+	if input.FraudThreshold == 0 {
+		input.FraudAction = ""
+		return nil
+	}
+	if input.FraudThreshold < 0 || input.FraudThreshold > fraud.MaxScore {
+		return ErrInvalidInput
+	}
+
+	if input.FraudAction == "" {
+		input.FraudAction = FraudActionFlag
+	}
+	if input.FraudAction != FraudActionBlock && input.FraudAction != FraudActionFlag && input.FraudAction != FraudActionReview {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// fraudOutcome returns the review status a scored claim starts with, or
+// ErrClaimBlocked when the benefit blocks claims scoring that high
+func fraudOutcome(benefit *models.Benefit, assessment fraud.Assessment) (string, error) {
+	if benefit.FraudThreshold <= 0 || assessment.Score < benefit.FraudThreshold {
+		return "", nil
+	}
+
+	switch benefit.FraudAction {
+	case FraudActionBlock:
+		return "", ErrClaimBlocked
+	case FraudActionReview:
+		return ReviewPending, nil
+	}
+	return ReviewFlagged, nil
+}
+
+// ReviewClaim settles a flagged or held claim on one of the creator's benefits.
+// Approving releases held codes to the claimer; rejecting revokes the claim and
+// returns its codes to the pool.
+func (s *BenefitService) ReviewClaim(ctx context.Context, userID uint, benefitUUID string, claimID uint, input ReviewClaimInput) (*models.Claim, error) {
+	if input.Decision != DecisionApprove && input.Decision != DecisionReject {
+		return nil, ErrInvalidInput
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Decision == DecisionReject && input.Reason == "" {
+		input.Reason = "rejected during fraud review"
+	}
+
+	tx := db.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var benefit models.Benefit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ? AND creator_id = ?", benefitUUID, userID).First(&benefit).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var claim models.Claim
+	if err := tx.Where("id = ? AND benefit_id = ? AND status = ?", claimID, benefit.ID, "active").Preload("Items").First(&claim).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClaimNotFound
+		}
+		return nil, err
+	}
+
+	if claim.ReviewStatus != ReviewFlagged && claim.ReviewStatus != ReviewPending {
+		tx.Rollback()
+		return nil, ErrClaimNotUnderReview
+	}
+
+	if input.Decision == DecisionReject {
+		if err := revokeClaim(ctx, tx, userID, &benefit, &claim, RevokeClaimInput{Reason: input.Reason, CodeAction: CodeActionReturn}); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Record the decision on the claim
+	previous := claim.ReviewStatus
+	now := time.Now()
+	claim.ReviewStatus = ReviewApproved
+	if input.Decision == DecisionReject {
+		claim.ReviewStatus = ReviewRejected
+	}
+	claim.ReviewedAt = &now
+	claim.ReviewedBy = &userID
+	if err := tx.Model(&claim).Select("review_status", "reviewed_at", "reviewed_by").Updates(&claim).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err := audit.Record(ctx, tx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionClaimReviewed,
+		TargetType: audit.TargetClaim,
+		TargetID:   strconv.FormatUint(uint64(claim.ID), 10),
+		BenefitID:  &benefit.ID,
+		Before:     map[string]interface{}{"review_status": previous},
+		After: map[string]interface{}{
+			"review_status": claim.ReviewStatus,
+			"reason":        input.Reason,
+			"risk_score":    claim.RiskScore,
+		},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Held codes become visible now, so tell the claimer
+	if previous == ReviewPending && claim.ReviewStatus == ReviewApproved {
+		err := outbox.Publish(tx, events.ClaimApproved, events.BenefitEvent{
+			Benefit: benefitPayload(&benefit),
+			Claim:   claimPayload(&claim),
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return &claim, nil
+}
+
+// claimPayload describes a claim in domain event payloads
+func claimPayload(claim *models.Claim) *events.ClaimPayload {
+	count := 0
+	for _, item := range claim.Items {
+		if item.ReplacedAt == nil {
+			count++
+		}
+	}
+
+	return &events.ClaimPayload{
+		ID:            claim.ID,
+		UserID:        claim.UserID,
+		OAuthProvider: claim.OAuthProvider,
+		ClaimedAt:     claim.ClaimedAt,
+		CodeCount:     count,
+		ReviewStatus:  claim.ReviewStatus,
+	}
+}
@@ -0,0 +1,61 @@
+package benefit
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Preview is what link previews of a claim page may show. It deliberately omits
+// claim conditions, codes and anything else only visible on the page itself.
+type Preview struct {
+	UUID        string
+	Title       string
+	Description string
+	Creator     string // 发布者的用户名
+	Remaining   int
+	Total       int
+	ExpiresAt   time.Time
+}
+
+// GetPreview returns the link preview of a benefit. Benefits that are private, not
+// active or expired have no preview and yield ErrNotFound, exactly like unknown
+// UUIDs, so that previews cannot be used to probe them.
+func (s *BenefitService) GetPreview(ctx context.Context, benefitUUID string) (*Preview, error) {
+	var benefit models.Benefit
+	err := db.DB.WithContext(ctx).
+		Where("uuid = ? AND status = ? AND private = ?", benefitUUID, "active", false).
+		First(&benefit).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if benefit.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	var creator models.User
+	if err := db.DB.WithContext(ctx).Select("username").First(&creator, benefit.CreatorID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	remaining := benefit.TotalCount - benefit.ClaimedCount
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &Preview{
+		UUID:        benefit.UUID,
+		Title:       benefit.Title,
+		Description: benefit.Description,
+		Creator:     creator.Username,
+		Remaining:   remaining,
+		Total:       benefit.TotalCount,
+		ExpiresAt:   benefit.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package benefit
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/audit"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values for RevokeClaimInput.CodeAction
+const (
+	// CodeActionReturn puts the revoked codes back into the pool
+	CodeActionReturn = "return"
+
+	// CodeActionBurn marks the revoked codes as unusable
+	CodeActionBurn = "burn"
+)
+
+// RevokeClaimInput represents the input for revoking a claim
+type RevokeClaimInput struct {
+	Reason     string `json:"reason" binding:"required"`
+	CodeAction string `json:"code_action"` // return/burn，默认 burn
+	BanUser    bool   `json:"ban_user"`    // 禁止该用户领取发布者今后的福利
+}
+
+// RevokeClaim voids a claim on one of the creator's benefits. The claim record is kept
+// with the revocation reason; its codes are either returned to the pool or burned.
+func (s *BenefitService) RevokeClaim(ctx context.Context, userID uint, benefitUUID string, claimID uint, input RevokeClaimInput) (*models.Claim, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return nil, ErrInvalidInput
+	}
+	if input.CodeAction == "" {
+		input.CodeAction = CodeActionBurn
+	}
+	if input.CodeAction != CodeActionReturn && input.CodeAction != CodeActionBurn {
+		return nil, ErrInvalidInput
+	}
+
+	tx := db.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var benefit models.Benefit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ? AND creator_id = ?", benefitUUID, userID).First(&benefit).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var claim models.Claim
+	if err := tx.Where("id = ? AND benefit_id = ? AND status = ?", claimID, benefit.ID, "active").Preload("Items").First(&claim).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClaimNotFound
+		}
+		return nil, err
+	}
+
+	if err := revokeClaim(ctx, tx, userID, &benefit, &claim, input); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return &claim, nil
+}
+
+// revokeClaim voids a claim inside tx, returning or burning its codes, updating the
+// benefit's counters and recording the revocation. The benefit row must be locked.
+func revokeClaim(ctx context.Context, tx *gorm.DB, userID uint, benefit *models.Benefit, claim *models.Claim, input RevokeClaimInput) error {
+	// 已被替换的兑换码早已作废，只处理仍然有效的兑换码
+	codeIDs := []uint{}
+	for _, item := range claim.Items {
+		if item.ReplacedAt == nil {
+			codeIDs = append(codeIDs, item.CodeID)
+		}
+	}
+	if len(codeIDs) == 0 && claim.CodeID > 0 {
+		codeIDs = append(codeIDs, claim.CodeID)
+	}
+
+	if len(codeIDs) > 0 {
+		updates := map[string]interface{}{"status": "burned"}
+		if input.CodeAction == CodeActionReturn {
+			updates = map[string]interface{}{"status": "available", "claimed_by": nil, "claimed_at": nil}
+		}
+		if err := tx.Model(&models.RedemptionCode{}).Where("id IN ?", codeIDs).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	// Void the claim
+	previous := claim.Status
+	now := time.Now()
+	claim.Status = "revoked"
+	claim.RevokedAt = &now
+	claim.RevokedBy = &userID
+	claim.RevokeReason = input.Reason
+	if err := tx.Model(claim).Select("status", "revoked_at", "revoked_by", "revoke_reason").Updates(claim).Error; err != nil {
+		return err
+	}
+
+	// Update the counters; burned codes no longer count towards the total
+	benefit.ClaimedCount -= len(codeIDs)
+	if benefit.ClaimedCount < 0 {
+		benefit.ClaimedCount = 0
+	}
+	if input.CodeAction == CodeActionBurn {
+		benefit.TotalCount -= len(codeIDs)
+	}
+	if err := tx.Save(benefit).Error; err != nil {
+		return err
+	}
+
+	// Ban the user from the creator's future benefits
+	if input.BanUser {
+		ban := models.CreatorBan{
+			CreatorID: userID,
+			UserID:    claim.UserID,
+			Reason:    input.Reason,
+			CreatedAt: now,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ban).Error; err != nil {
+			return err
+		}
+
+		err := audit.Record(ctx, tx, audit.Event{
+			ActorID:    &userID,
+			Action:     audit.ActionUserBannedByCreator,
+			TargetType: audit.TargetUser,
+			TargetID:   strconv.FormatUint(uint64(claim.UserID), 10),
+			BenefitID:  &benefit.ID,
+			After:      map[string]interface{}{"reason": input.Reason},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the revocation and its reason in the audit log
+	return audit.Record(ctx, tx, audit.Event{
+		ActorID:    &userID,
+		Action:     audit.ActionClaimRevoked,
+		TargetType: audit.TargetClaim,
+		TargetID:   strconv.FormatUint(uint64(claim.ID), 10),
+		BenefitID:  &benefit.ID,
+		Before:     map[string]interface{}{"status": previous},
+		After: map[string]interface{}{
+			"status":      claim.Status,
+			"reason":      input.Reason,
+			"code_action": input.CodeAction,
+			"code_ids":    codeIDs,
+			"ban_user":    input.BanUser,
+		},
+	})
+}
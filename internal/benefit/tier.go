@@ -0,0 +1,187 @@
+package benefit
+
+import (
+	"errors"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Supported values for Benefit.AllocationMode
+const (
+	// AllocationSequential hands out codes in import order, ignoring tier weights
+	AllocationSequential = "sequential"
+
+	// AllocationWeighted picks a tier at random, weighted by TierWeight
+	AllocationWeighted = "weighted"
+)
+
+// TierInput represents a group of codes sharing the same prize tier
+type TierInput struct {
+	Name     string               `json:"name" binding:"required"`
+	Weight   int                  `json:"weight"`
+	Value    float64              `json:"value"`
+	Codes    []string             `json:"codes"`
+	Metadata *models.CodeMetadata `json:"metadata"` // 覆盖 CreateBenefitInput.CodeMetadata 中的默认值
+}
+
+// TierStat summarizes the codes of a single tier within a benefit
+type TierStat struct {
+	Tier    string  `json:"tier"`
+	Weight  int     `json:"weight"`
+	Value   float64 `json:"value"`
+	Total   int     `json:"total"`
+	Claimed int     `json:"claimed"`
+}
+
+// GetTierDistribution returns how codes of a benefit are spread across tiers and how many of each were claimed
+func (s *BenefitService) GetTierDistribution(userID uint, benefitUUID string) ([]TierStat, error) {
+	benefit, err := getOwnedBenefit(userID, benefitUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []TierStat
+	err = db.DB.Model(&models.RedemptionCode{}).
+		Select("tier, MAX(tier_weight) AS weight, MAX(tier_value) AS value, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status = 'claimed' THEN 1 ELSE 0 END) AS claimed").
+		Where("benefit_id = ?", benefit.ID).
+		Group("tier").
+		Order("value DESC, tier").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// validateTierRules checks that every rule references an existing tier and that thresholds increase
+func validateTierRules(rules []models.TierRule, codes []models.RedemptionCode) error {
+	tiers := make(map[string]bool)
+	for _, code := range codes {
+		tiers[code.Tier] = true
+	}
+
+	last := 0
+	for _, rule := range rules {
+		if !tiers[rule.Tier] || rule.FirstN <= last {
+			return ErrInvalidInput
+		}
+		last = rule.FirstN
+	}
+
+	return nil
+}
+
+// allocateCode picks the next available code for the claim with the given sequence number.
+// Tier rules take precedence; when no rule applies (or its tier is exhausted) the benefit's
+// allocation mode decides, see allocateUnreserved.
+// It returns gorm.ErrRecordNotFound when nothing is left to hand out.
+func allocateCode(tx *gorm.DB, benefit *models.Benefit, seq int) (*models.RedemptionCode, error) {
+	for _, rule := range benefit.TierRules {
+		if seq > rule.FirstN {
+			continue
+		}
+
+		code, err := firstAvailable(availableCodes(tx, benefit.ID).Where("tier = ?", rule.Tier))
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return code, err
+		}
+		break
+	}
+
+	return allocateUnreserved(tx, benefit, seq)
+}
+
+// allocateUnreserved picks a code by the benefit's allocation mode among the tiers not
+// reserved for later claimers. A rule reserves its tier only while its window is open,
+// i.e. for claims numbered up to FirstN; afterwards the codes left in the tier join the
+// pool, so that they are not stranded.
+func allocateUnreserved(tx *gorm.DB, benefit *models.Benefit, seq int) (*models.RedemptionCode, error) {
+	reserved := make([]string, 0, len(benefit.TierRules))
+	for _, rule := range benefit.TierRules {
+		if seq <= rule.FirstN {
+			reserved = append(reserved, rule.Tier)
+		}
+	}
+
+	query := func() *gorm.DB {
+		q := availableCodes(tx, benefit.ID)
+		if len(reserved) > 0 {
+			q = q.Where("tier NOT IN ?", reserved)
+		}
+		return q
+	}
+
+	if benefit.AllocationMode == AllocationWeighted {
+		tier, err := pickWeightedTier(query())
+		if err != nil {
+			return nil, err
+		}
+		return firstAvailable(query().Where("tier = ?", tier))
+	}
+
+	return firstAvailable(query())
+}
+
+// nextClaimSeq returns the sequence number of the next claim on a benefit, which
+// tier rules are matched against. Revoked claims, including those rejected in review,
+// do not count, so they give their slot back.
+func nextClaimSeq(tx *gorm.DB, benefitID uint) (int, error) {
+	var previousClaims int64
+	err := tx.Model(&models.Claim{}).Where("benefit_id = ? AND status = ?", benefitID, "active").Count(&previousClaims).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(previousClaims) + 1, nil
+}
+
+// availableCodes starts a query over the available codes of a benefit. Codes past
+// their own expiry date are left out, so they are never handed out.
+func availableCodes(tx *gorm.DB, benefitID uint) *gorm.DB {
+	return tx.Model(&models.RedemptionCode{}).
+		Where("benefit_id = ? AND status = ?", benefitID, "available").
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now())
+}
+
+// firstAvailable returns the earliest imported code matching the query
+func firstAvailable(query *gorm.DB) (*models.RedemptionCode, error) {
+	var code models.RedemptionCode
+	if err := query.Order("id").First(&code).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// pickWeightedTier chooses one of the tiers with available codes, weighted by TierWeight
+func pickWeightedTier(query *gorm.DB) (string, error) {
+	var tiers []struct {
+		Tier   string
+		Weight int
+	}
+	if err := query.Select("tier, MAX(tier_weight) AS weight").Group("tier").Scan(&tiers).Error; err != nil {
+		return "", err
+	}
+
+	if len(tiers) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	total := 0
+	for i := range tiers {
+		if tiers[i].Weight <= 0 {
+			tiers[i].Weight = 1
+		}
+		total += tiers[i].Weight
+	}
+
+	n := rand.IntN(total)
+	for _, t := range tiers {
+		if n < t.Weight {
+			return t.Tier, nil
+		}
+		n -= t.Weight
+	}
+
+	return tiers[len(tiers)-1].Tier, nil
+}
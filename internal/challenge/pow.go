@@ -0,0 +1,174 @@
+package challenge
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Challenge types a benefit can require before claiming
+const (
+	TypeNone    = ""
+	TypePoW     = "pow"
+	TypeCaptcha = "captcha"
+)
+
+// Proof-of-work difficulty bounds, in leading zero bits of the hash
+const (
+	DefaultDifficulty = 20
+	MinDifficulty     = 8
+	MaxDifficulty     = 28
+)
+
+// powTTL is how long an issued proof-of-work challenge stays valid
+const powTTL = 5 * time.Minute
+
+var (
+	// ErrInvalidToken indicates a tampered, foreign or malformed challenge token
+	ErrInvalidToken = errors.New("invalid challenge token")
+
+	// ErrExpired indicates a challenge token past its expiry
+	ErrExpired = errors.New("challenge expired")
+
+	// ErrInvalidSolution indicates a solution that does not meet the difficulty
+	ErrInvalidSolution = errors.New("invalid challenge solution")
+)
+
+// PoW is a hashcash-style challenge: find a Solution such that
+// SHA-256(Token + Solution) starts with at least Difficulty zero bits.
+type PoW struct {
+	Algorithm  string    `json:"algorithm"` // 固定为 sha256
+	Token      string    `json:"token"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// powClaims is the signed content of a challenge token
+type powClaims struct {
+	Benefit    string `json:"b"`
+	Difficulty int    `json:"d"`
+	Expires    int64  `json:"e"`
+	Nonce      string `json:"n"`
+}
+
+// IssuePoW creates a signed proof-of-work challenge bound to a benefit
+func IssuePoW(benefitUUID string, difficulty int) (*PoW, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(powTTL)
+	body, err := json.Marshal(powClaims{
+		Benefit:    benefitUUID,
+		Difficulty: difficulty,
+		Expires:    expiresAt.Unix(),
+		Nonce:      hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(sign(payload))
+
+	return &PoW{
+		Algorithm:  "sha256",
+		Token:      token,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// VerifyPoW checks a solution for a token issued for the benefit. It returns the
+// token's expiry so the caller can remember the token until then and reject replays.
+func VerifyPoW(benefitUUID, token, solution string) (time.Time, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(payload)) {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	var claims powClaims
+	if err := json.Unmarshal(body, &claims); err != nil || claims.Benefit != benefitUUID {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	expiresAt := time.Unix(claims.Expires, 0)
+	if time.Now().After(expiresAt) {
+		return time.Time{}, ErrExpired
+	}
+
+	if solution == "" || len(solution) > 64 {
+		return time.Time{}, ErrInvalidSolution
+	}
+	hash := sha256.Sum256([]byte(token + solution))
+	if leadingZeroBits(hash[:]) < claims.Difficulty {
+		return time.Time{}, ErrInvalidSolution
+	}
+
+	return expiresAt, nil
+}
+
+// TokenKey identifies a token when remembering it as used
+func TokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// leadingZeroBits counts the zero bits at the start of b
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
+
+var (
+	secretMu  sync.Mutex
+	secretKey []byte
+)
+
+// SetSecret sets the key challenge tokens are signed with; all replicas must share it
+func SetSecret(secret string) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretKey = []byte(secret)
+}
+
+// sign computes the HMAC of a token payload
+func sign(payload string) []byte {
+	secretMu.Lock()
+	if len(secretKey) == 0 {
+		// 未配置密钥时使用随机密钥，重启后已签发的挑战将失效
+		secretKey = make([]byte, 32)
+		rand.Read(secretKey)
+	}
+	key := secretKey
+	secretMu.Unlock()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("pow:" + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,234 @@
+// Package clientip works out who a request really came from when the server runs
+// behind reverse proxies. Forwarding headers are only believed when the hop that
+// set them is a trusted proxy, so clients cannot spoof their address, scheme or host.
+package clientip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidProxy indicates a trusted proxy entry that is neither an IP nor a CIDR
+var ErrInvalidProxy = errors.New("invalid trusted proxy")
+
+// CloudflareHeader carries the visitor address set by Cloudflare
+const CloudflareHeader = "CF-Connecting-IP"
+
+// CloudflareRanges are the published Cloudflare edge networks, see https://www.cloudflare.com/ips/
+var CloudflareRanges = []string{
+	"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+	"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+	"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+	"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+	"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+	"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+}
+
+// Config describes the proxies in front of the server
+type Config struct {
+	TrustedProxies []string // 可信代理的 IP 或 CIDR
+	IPHeaders      []string // 按顺序查找客户端地址的请求头，如 X-Forwarded-For
+	ProtoHeader    string   // 原始协议所在的请求头，空字符串表示不读取
+	HostHeader     string   // 原始 Host 所在的请求头，空字符串表示不读取
+	Cloudflare     bool     // 信任 Cloudflare 边缘节点并读取 CF-Connecting-IP
+	CloudflareIPs  []string // 覆盖内置的 Cloudflare 网段
+}
+
+// DefaultConfig trusts a proxy on the same machine, as in nginx.conf.example
+func DefaultConfig() Config {
+	return Config{
+		TrustedProxies: []string{"127.0.0.0/8", "::1"},
+		IPHeaders:      []string{"X-Forwarded-For", "X-Real-IP"},
+		ProtoHeader:    "X-Forwarded-Proto",
+		HostHeader:     "X-Forwarded-Host",
+	}
+}
+
+// Resolver resolves the client address, scheme and host of requests
+type Resolver struct {
+	trusted     []*net.IPNet
+	cloudflare  []*net.IPNet
+	ipHeaders   []string
+	protoHeader string
+	hostHeader  string
+}
+
+// NewResolver creates a resolver for the given proxy configuration
+func NewResolver(cfg Config) (*Resolver, error) {
+	trusted, err := parseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{
+		trusted:     trusted,
+		protoHeader: cfg.ProtoHeader,
+		hostHeader:  cfg.HostHeader,
+	}
+	for _, header := range cfg.IPHeaders {
+		r.ipHeaders = append(r.ipHeaders, http.CanonicalHeaderKey(header))
+	}
+
+	if cfg.Cloudflare {
+		ranges := cfg.CloudflareIPs
+		if len(ranges) == 0 {
+			ranges = CloudflareRanges
+		}
+		if r.cloudflare, err = parseNetworks(ranges); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// ClientIP returns the address of the client that sent the request. Forwarding
+// headers are walked from the nearest hop outwards, skipping trusted proxies; the
+// first untrusted address is the client. CF-Connecting-IP is only honoured when the
+// request actually passed through a Cloudflare edge node.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := remoteIP(req)
+	if remote == nil {
+		return ""
+	}
+	if !r.isTrusted(remote) && !r.isCloudflare(remote) {
+		return remote.String()
+	}
+
+	viaCloudflare := r.isCloudflare(remote)
+	for _, header := range r.ipHeaders {
+		values := req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if r.isCloudflare(ip) {
+				viaCloudflare = true
+				continue
+			}
+			if r.isTrusted(ip) {
+				continue
+			}
+			if viaCloudflare {
+				if cf := r.cloudflareIP(req); cf != "" {
+					return cf
+				}
+			}
+			return ip.String()
+		}
+	}
+
+	if viaCloudflare {
+		if cf := r.cloudflareIP(req); cf != "" {
+			return cf
+		}
+	}
+	return remote.String()
+}
+
+// Scheme returns "https" or "http" as seen by the client
+func (r *Resolver) Scheme(req *http.Request) string {
+	if r.protoHeader != "" && r.fromProxy(req) {
+		proto := strings.TrimSpace(strings.Split(req.Header.Get(r.protoHeader), ",")[0])
+		if proto == "https" || proto == "http" {
+			return proto
+		}
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host the client addressed
+func (r *Resolver) Host(req *http.Request) string {
+	if r.hostHeader != "" && r.fromProxy(req) {
+		if host := strings.TrimSpace(strings.Split(req.Header.Get(r.hostHeader), ",")[0]); host != "" {
+			return host
+		}
+	}
+	return req.Host
+}
+
+// BaseURL returns scheme://host of the request as seen by the client
+func (r *Resolver) BaseURL(req *http.Request) string {
+	return fmt.Sprintf("%s://%s", r.Scheme(req), r.Host(req))
+}
+
+// fromProxy reports whether the request was handed to us by a trusted proxy
+func (r *Resolver) fromProxy(req *http.Request) bool {
+	remote := remoteIP(req)
+	return remote != nil && (r.isTrusted(remote) || r.isCloudflare(remote))
+}
+
+// cloudflareIP returns the valid CF-Connecting-IP of the request, if any
+func (r *Resolver) cloudflareIP(req *http.Request) string {
+	ip := net.ParseIP(strings.TrimSpace(req.Header.Get(CloudflareHeader)))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	return contains(r.trusted, ip)
+}
+
+func (r *Resolver) isCloudflare(ip net.IP) bool {
+	return contains(r.cloudflare, ip)
+}
+
+// remoteIP parses the address of the peer connected to us
+func remoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// contains reports whether ip is part of any of the networks
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetworks parses IPs and CIDRs into networks; a bare IP becomes a single-address network
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidProxy, entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidProxy, entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
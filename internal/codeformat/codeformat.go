@@ -0,0 +1,88 @@
+package codeformat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnknownFormat indicates the requested code format is not registered
+	ErrUnknownFormat = errors.New("unknown code format")
+
+	// ErrInvalidPattern indicates a custom regex pattern could not be compiled
+	ErrInvalidPattern = errors.New("invalid code pattern")
+)
+
+// Validator checks whether a redemption code is well-formed
+type Validator interface {
+	// Validate returns a descriptive error if the code is malformed
+	Validate(code string) error
+}
+
+// regexValidator validates codes against a regular expression
+type regexValidator struct {
+	re   *regexp.Regexp
+	hint string
+}
+
+// Validate implements Validator
+func (v *regexValidator) Validate(code string) error {
+	if !v.re.MatchString(code) {
+		return fmt.Errorf("expected format %s", v.hint)
+	}
+	return nil
+}
+
+// builtin holds the named formats shipped with the service
+var builtin = map[string]Validator{
+	"steam": mustRegex(`^[A-Z0-9]{5}-[A-Z0-9]{5}-[A-Z0-9]{5}$`, "XXXXX-XXXXX-XXXXX"),
+	"psn":   mustRegex(`^[A-Z0-9]{4}-[A-Z0-9]{4}-[A-Z0-9]{4}$`, "XXXX-XXXX-XXXX"),
+	"xbox":  mustRegex(`^[A-Z0-9]{5}(-[A-Z0-9]{5}){4}$`, "XXXXX-XXXXX-XXXXX-XXXXX-XXXXX"),
+	"uuid":  mustRegex(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`, "UUID"),
+}
+
+// Formats returns the names of the built-in formats
+func Formats() []string {
+	names := make([]string, 0, len(builtin)+1)
+	for name := range builtin {
+		names = append(names, name)
+	}
+	names = append(names, "regex")
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the validator for a format. An empty format means no validation
+// and yields a nil Validator; the "regex" format compiles the given pattern.
+func Lookup(format, pattern string) (Validator, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "":
+		return nil, nil
+	case "regex":
+		re, err := regexp.Compile(anchor(pattern))
+		if pattern == "" || err != nil {
+			return nil, ErrInvalidPattern
+		}
+		return &regexValidator{re: re, hint: pattern}, nil
+	}
+
+	v, ok := builtin[format]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	return v, nil
+}
+
+// anchor makes sure a user supplied pattern matches the whole code
+func anchor(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// mustRegex builds a regexValidator for a built-in format
+func mustRegex(expr, hint string) Validator {
+	return &regexValidator{re: regexp.MustCompile(expr), hint: hint}
+}
@@ -0,0 +1,507 @@
+// Package config loads the server configuration. Defaults are overlaid by an optional
+// YAML or TOML file, which is in turn overlaid by environment variables, so existing
+// .env based deployments keep working unchanged. The result is validated once at
+// startup and handed to each subsystem as a typed struct.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"giftredeem/internal/auth"
+	"giftredeem/internal/challenge"
+	"giftredeem/internal/clientip"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/notify"
+	"giftredeem/internal/ratelimit"
+	"giftredeem/internal/site"
+	"giftredeem/internal/tracing"
+	"giftredeem/internal/webhook"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Modes the server can run in
+const (
+	ModeDev        = "dev"
+	ModeProduction = "production"
+)
+
+// ErrInvalidConfig indicates a configuration that failed validation
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config is the complete server configuration. Each field names its file key and the
+// environment variable overriding it; fields tagged secret are redacted when printed.
+type Config struct {
+	Mode      string          `yaml:"mode" toml:"mode" env:"APP_MODE"` // dev/production
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	OAuth     OAuthConfig     `yaml:"oauth" toml:"oauth"`
+	Site      SiteConfig      `yaml:"site" toml:"site"`
+	Proxy     ProxyConfig     `yaml:"proxy" toml:"proxy"`
+	SMTP      SMTPConfig      `yaml:"smtp" toml:"smtp"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Challenge ChallengeConfig `yaml:"challenge" toml:"challenge"`
+	Benefits  BenefitsConfig  `yaml:"benefits" toml:"benefits"`
+	Webhook   WebhookConfig   `yaml:"webhook" toml:"webhook"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+	Logging   LoggingConfig   `yaml:"logging" toml:"logging"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port            int      `yaml:"port" toml:"port" env:"PORT"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"` // 等待进行中的请求完成的最长时间
+	FrontendDir     string   `yaml:"frontend_dir" toml:"frontend_dir" env:"FRONTEND_DIR"`             // 前端构建目录，为空时使用嵌入的前端，未嵌入时使用 ./frontend/dist
+}
+
+// DatabaseConfig configures the MySQL connection
+type DatabaseConfig struct {
+	Username string `yaml:"username" toml:"username" env:"DB_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+}
+
+// AuthConfig configures authentication
+type AuthConfig struct {
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+}
+
+// OAuthConfig declares the OAuth providers users can log in with. When any provider
+// is configured, o_auth_providers is synced to match at startup and providers missing
+// from the configuration are disabled.
+type OAuthConfig struct {
+	EncryptionKey string                         `yaml:"encryption_key" toml:"encryption_key" env:"OAUTH_ENCRYPTION_KEY" secret:"true"` // 默认使用 JWT 密钥
+	Providers     map[string]OAuthProviderConfig `yaml:"providers" toml:"providers"`                                                    // 也可通过 OAUTH_<NAME>_* 环境变量设置
+}
+
+// OAuthProviderConfig declares one OAuth provider. Endpoints of well-known providers
+// such as linuxdo default to their public values.
+type OAuthProviderConfig struct {
+	DisplayName  string `yaml:"display_name" toml:"display_name"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret" secret:"true"`
+	AuthURL      string `yaml:"auth_url" toml:"auth_url"`
+	TokenURL     string `yaml:"token_url" toml:"token_url"`
+	UserInfoURL  string `yaml:"user_info_url" toml:"user_info_url"`
+	Scope        string `yaml:"scope" toml:"scope"`
+	Enabled      *bool  `yaml:"enabled,omitempty" toml:"enabled,omitempty"` // 默认启用
+	SortOrder    int    `yaml:"sort_order" toml:"sort_order"`
+}
+
+// SiteConfig configures the public addresses of the site
+type SiteConfig struct {
+	PublicURL            string   `yaml:"public_url" toml:"public_url" env:"PUBLIC_URL"`
+	FrontendURL          string   `yaml:"frontend_url" toml:"frontend_url" env:"FRONTEND_URL"`
+	AllowedHosts         []string `yaml:"allowed_hosts" toml:"allowed_hosts" env:"ALLOWED_HOSTS"`
+	OAuthCallbackPath    string   `yaml:"oauth_callback_path" toml:"oauth_callback_path" env:"OAUTH_CALLBACK_PATH"`
+	FrontendCallbackPath string   `yaml:"frontend_callback_path" toml:"frontend_callback_path" env:"FRONTEND_CALLBACK_PATH"`
+	FrontendLoginPath    string   `yaml:"frontend_login_path" toml:"frontend_login_path" env:"FRONTEND_LOGIN_PATH"`
+}
+
+// ProxyConfig configures the reverse proxies trusted for client addresses
+type ProxyConfig struct {
+	TrustedProxies  []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	IPHeaders       []string `yaml:"ip_headers" toml:"ip_headers" env:"FORWARDED_IP_HEADERS"`
+	ProtoHeader     string   `yaml:"proto_header" toml:"proto_header" env:"FORWARDED_PROTO_HEADER"`
+	HostHeader      string   `yaml:"host_header" toml:"host_header" env:"FORWARDED_HOST_HEADER"`
+	TrustCloudflare bool     `yaml:"trust_cloudflare" toml:"trust_cloudflare" env:"TRUST_CLOUDFLARE"`
+	CloudflareIPs   []string `yaml:"cloudflare_ips" toml:"cloudflare_ips" env:"CLOUDFLARE_IPS"`
+}
+
+// SMTPConfig configures email notifications; they are disabled without a host
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" toml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" toml:"from" env:"SMTP_FROM"`
+	TLS      string `yaml:"tls" toml:"tls" env:"SMTP_TLS"` // none/starttls/tls
+}
+
+// RateLimitConfig configures the token buckets of the rate-limited endpoints
+type RateLimitConfig struct {
+	Store        string          `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE"` // memory/mysql
+	OAuthIP      ratelimit.Limit `yaml:"oauth_ip" toml:"oauth_ip" env:"RATE_LIMIT_OAUTH_IP"`
+	ClaimIP      ratelimit.Limit `yaml:"claim_ip" toml:"claim_ip" env:"RATE_LIMIT_CLAIM_IP"`
+	ClaimUser    ratelimit.Limit `yaml:"claim_user" toml:"claim_user" env:"RATE_LIMIT_CLAIM_USER"`
+	ClaimBenefit ratelimit.Limit `yaml:"claim_benefit" toml:"claim_benefit" env:"RATE_LIMIT_CLAIM_BENEFIT"`
+	StreamsPerIP int             `yaml:"streams_per_ip" toml:"streams_per_ip" env:"RATE_LIMIT_STREAMS_PER_IP"` // 每个 IP 同时打开的实时推送连接数
+}
+
+// ChallengeConfig configures proof-of-work and CAPTCHA challenges
+type ChallengeConfig struct {
+	Secret           string `yaml:"secret" toml:"secret" env:"CHALLENGE_SECRET" secret:"true"` // 默认使用 JWT 密钥
+	CaptchaProvider  string `yaml:"captcha_provider" toml:"captcha_provider" env:"CAPTCHA_PROVIDER"`
+	CaptchaSecret    string `yaml:"captcha_secret" toml:"captcha_secret" env:"CAPTCHA_SECRET" secret:"true"`
+	CaptchaSiteKey   string `yaml:"captcha_site_key" toml:"captcha_site_key" env:"CAPTCHA_SITE_KEY"`
+	CaptchaVerifyURL string `yaml:"captcha_verify_url" toml:"captcha_verify_url" env:"CAPTCHA_VERIFY_URL"`
+}
+
+// BenefitsConfig configures background work on benefits
+type BenefitsConfig struct {
+	ExpiringWindow Duration `yaml:"expiring_window" toml:"expiring_window" env:"BENEFIT_EXPIRING_WINDOW"`
+}
+
+// WebhookConfig configures the delivery of webhooks
+type WebhookConfig struct {
+	AllowLoopback bool `yaml:"allow_loopback" toml:"allow_loopback" env:"WEBHOOK_ALLOW_LOOPBACK"` // 允许投递到本机地址，仅用于本地测试
+}
+
+// MetricsConfig configures the Prometheus endpoint
+type MetricsConfig struct {
+	Token string `yaml:"token" toml:"token" env:"METRICS_TOKEN" secret:"true"` // 非空时抓取 /metrics 需要携带该 Bearer 令牌
+}
+
+// LoggingConfig configures the structured logs
+type LoggingConfig struct {
+	Level  string            `yaml:"level" toml:"level" env:"LOG_LEVEL"`    // debug/info/warn/error
+	Format string            `yaml:"format" toml:"format" env:"LOG_FORMAT"` // text/json
+	Levels map[string]string `yaml:"levels" toml:"levels" env:"LOG_LEVELS"` // 按子系统覆盖，如 db=debug,auth=warn
+}
+
+// TracingConfig configures the export of OpenTelemetry spans
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled" toml:"enabled" env:"TRACING_ENABLED"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" env:"TRACING_ENDPOINT"` // OTLP/HTTP 采集器地址
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"` // 0~1
+}
+
+// Default returns the configuration used when nothing is configured
+func Default() Config {
+	proxy := clientip.DefaultConfig()
+	return Config{
+		Mode:   ModeProduction,
+		Server: ServerConfig{Port: 8080, ShutdownTimeout: Duration{30 * time.Second}},
+		Database: DatabaseConfig{
+			Username: "root",
+			Host:     "localhost",
+			Port:     3306,
+			Name:     "giftredeem",
+		},
+		Site: SiteConfig{
+			OAuthCallbackPath:    site.DefaultOAuthCallbackPath,
+			FrontendCallbackPath: site.DefaultFrontendCallbackPath,
+			FrontendLoginPath:    site.DefaultFrontendLoginPath,
+		},
+		Proxy: ProxyConfig{
+			TrustedProxies: proxy.TrustedProxies,
+			IPHeaders:      proxy.IPHeaders,
+			ProtoHeader:    proxy.ProtoHeader,
+			HostHeader:     proxy.HostHeader,
+		},
+		RateLimit: RateLimitConfig{
+			Store:        ratelimit.StoreMemory,
+			OAuthIP:      ratelimit.PerMinute(30, 30),
+			ClaimIP:      ratelimit.PerMinute(60, 30),
+			ClaimUser:    ratelimit.PerMinute(10, 5),
+			ClaimBenefit: ratelimit.PerSecond(50, 100),
+			StreamsPerIP: 10,
+		},
+		Benefits: BenefitsConfig{ExpiringWindow: Duration{24 * time.Hour}},
+		Logging:  LoggingConfig{Level: "info", Format: logging.FormatText},
+		Tracing: TracingConfig{
+			Endpoint:    tracing.DefaultEndpoint,
+			ServiceName: "giftredeem",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file at path (skipped when
+// path is empty) and the environment, then validates it. The configuration is
+// returned even when validation fails so that it can still be printed.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, cfg.Validate()
+}
+
+// loadFile overlays the YAML or TOML file at path; unknown keys are rejected
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Validate checks the configuration and fills in values derived from other settings.
+// All problems are reported together.
+func (c *Config) Validate() error {
+	var problems []error
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.Mode != ModeDev && c.Mode != ModeProduction {
+		fail("mode (APP_MODE) must be %s or %s", ModeDev, ModeProduction)
+	}
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		fail("server.port (PORT) must be between 1 and 65535")
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		fail("server.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive")
+	}
+
+	// 生产环境中拒绝使用默认的 JWT 密钥，否则任何人都能伪造登录令牌
+	if c.Auth.JWTSecret == "" || c.Auth.JWTSecret == auth.DevJWTSecret {
+		if c.Mode == ModeDev {
+			c.Auth.JWTSecret = auth.DevJWTSecret
+		} else {
+			fail("auth.jwt_secret (JWT_SECRET) must be set to a random value outside dev mode")
+		}
+	}
+
+	if c.Database.Host == "" || c.Database.Name == "" {
+		fail("database.host (DB_HOST) and database.name (DB_NAME) are required")
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		fail("database.port (DB_PORT) must be between 1 and 65535")
+	}
+
+	for _, provider := range c.OAuth.ProviderConfigs() {
+		if err := provider.Validate(); err != nil {
+			fail("oauth: %v", err)
+		}
+	}
+
+	siteConfig := c.Site.Site()
+	if err := siteConfig.Validate(); err != nil {
+		fail("site: %v", err)
+	}
+	if _, err := clientip.NewResolver(c.Proxy.ClientIP()); err != nil {
+		fail("proxy: %v", err)
+	}
+
+	if c.SMTP.Host != "" {
+		if err := c.SMTP.Mailer().Validate(); err != nil {
+			fail("smtp: %v", err)
+		}
+		// 邮件中的链接无法从请求推断地址，未设置时会指向 localhost
+		if c.Site.PublicURL == "" && c.Mode != ModeDev {
+			fail("site.public_url (PUBLIC_URL) is required outside dev mode when smtp.host (SMTP_HOST) is set, links in emails are built from it")
+		}
+	}
+
+	if c.RateLimit.Store != ratelimit.StoreMemory && c.RateLimit.Store != ratelimit.StoreMySQL {
+		fail("rate_limit.store (RATE_LIMIT_STORE) must be %s or %s", ratelimit.StoreMemory, ratelimit.StoreMySQL)
+	}
+	for name, limit := range map[string]ratelimit.Limit{
+		"oauth_ip":      c.RateLimit.OAuthIP,
+		"claim_ip":      c.RateLimit.ClaimIP,
+		"claim_user":    c.RateLimit.ClaimUser,
+		"claim_benefit": c.RateLimit.ClaimBenefit,
+	} {
+		if limit.Rate <= 0 || limit.Burst <= 0 {
+			fail("rate_limit.%s must allow at least one request", name)
+		}
+	}
+	if c.RateLimit.StreamsPerIP <= 0 {
+		fail("rate_limit.streams_per_ip (RATE_LIMIT_STREAMS_PER_IP) must be positive")
+	}
+
+	if c.Challenge.CaptchaSecret != "" {
+		if _, err := c.Challenge.Captcha().VerifyEndpoint(); err != nil {
+			fail("challenge: %v", err)
+		}
+	}
+
+	if err := c.Logging.Logging().Validate(); err != nil {
+		fail("logging: %v", err)
+	}
+
+	if err := c.Tracing.Tracing().Validate(); err != nil {
+		fail("tracing: %v", err)
+	}
+
+	if c.Benefits.ExpiringWindow.Duration <= 0 {
+		fail("benefits.expiring_window (BENEFIT_EXPIRING_WINDOW) must be positive")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(problems...))
+	}
+	return nil
+}
+
+// IsDev reports whether the server runs in dev mode
+func (c *Config) IsDev() bool {
+	return c.Mode == ModeDev
+}
+
+// DB returns the database connection settings
+func (c DatabaseConfig) DB() db.Config {
+	return db.Config{
+		Username: c.Username,
+		Password: c.Password,
+		Host:     c.Host,
+		Port:     c.Port,
+		Name:     c.Name,
+	}
+}
+
+// Site returns the site addresses
+func (c SiteConfig) Site() site.Config {
+	return site.Config{
+		PublicURL:            strings.TrimRight(c.PublicURL, "/"),
+		FrontendURL:          strings.TrimRight(c.FrontendURL, "/"),
+		AllowedHosts:         c.AllowedHosts,
+		OAuthCallbackPath:    c.OAuthCallbackPath,
+		FrontendCallbackPath: c.FrontendCallbackPath,
+		FrontendLoginPath:    c.FrontendLoginPath,
+	}
+}
+
+// ClientIP returns the trusted proxy settings
+func (c ProxyConfig) ClientIP() clientip.Config {
+	return clientip.Config{
+		TrustedProxies: c.TrustedProxies,
+		IPHeaders:      c.IPHeaders,
+		ProtoHeader:    c.ProtoHeader,
+		HostHeader:     c.HostHeader,
+		Cloudflare:     c.TrustCloudflare,
+		CloudflareIPs:  c.CloudflareIPs,
+	}
+}
+
+// Mailer returns the SMTP settings with defaults applied
+func (c SMTPConfig) Mailer() notify.SMTPConfig {
+	return notify.SMTPConfig{
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.Username,
+		Password: c.Password,
+		From:     c.From,
+		TLS:      c.TLS,
+	}.WithDefaults()
+}
+
+// Webhooks returns the webhook delivery settings
+func (c WebhookConfig) Webhooks() webhook.Config {
+	return webhook.Config{AllowLoopback: c.AllowLoopback}
+}
+
+// Logging returns the logging settings
+func (c LoggingConfig) Logging() logging.Config {
+	return logging.Config{
+		Level:  c.Level,
+		Format: c.Format,
+		Levels: c.Levels,
+	}
+}
+
+// Tracing returns the tracing settings
+func (c TracingConfig) Tracing() tracing.Config {
+	return tracing.Config{
+		Enabled:     c.Enabled,
+		Endpoint:    c.Endpoint,
+		ServiceName: c.ServiceName,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// Captcha returns the CAPTCHA settings
+func (c ChallengeConfig) Captcha() challenge.CaptchaConfig {
+	return challenge.CaptchaConfig{
+		Provider:  c.CaptchaProvider,
+		VerifyURL: c.CaptchaVerifyURL,
+		Secret:    c.CaptchaSecret,
+		SiteKey:   c.CaptchaSiteKey,
+	}
+}
+
+// ProviderConfigs returns the configured OAuth providers ordered by name, with the
+// endpoints of well-known providers filled in
+func (c OAuthConfig) ProviderConfigs() []auth.ProviderConfig {
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := make([]auth.ProviderConfig, 0, len(names))
+	for _, name := range names {
+		p := c.Providers[name]
+		providers = append(providers, auth.ProviderConfig{
+			Name:         name,
+			DisplayName:  p.DisplayName,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			UserInfoURL:  p.UserInfoURL,
+			Scope:        p.Scope,
+			Enabled:      p.Enabled == nil || *p.Enabled,
+			SortOrder:    p.SortOrder,
+		}.WithPreset())
+	}
+	return providers
+}
+
+// RequiredProviders returns the names of the configured providers that are enabled
+func (c OAuthConfig) RequiredProviders() []string {
+	var names []string
+	for _, provider := range c.ProviderConfigs() {
+		if provider.Enabled {
+			names = append(names, provider.Name)
+		}
+	}
+	return names
+}
+
+// EncryptionKey returns the key OAuth client secrets are encrypted with, falling back to the JWT secret
+func (c *Config) EncryptionKey() string {
+	if c.OAuth.EncryptionKey != "" {
+		return c.OAuth.EncryptionKey
+	}
+	return c.Auth.JWTSecret
+}
+
+// ChallengeSecret returns the key challenge tokens are signed with, falling back to the JWT secret
+func (c *Config) ChallengeSecret() string {
+	if c.Challenge.Secret != "" {
+		return c.Challenge.Secret
+	}
+	return c.Auth.JWTSecret
+}
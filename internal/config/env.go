@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces secrets in printed configurations
+const redactedValue = "******"
+
+// Duration is a time.Duration written as "24h" or "90m" in files and the environment
+type Duration struct {
+	time.Duration
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	d.Duration = value
+	return nil
+}
+
+// applyEnv overrides every field tagged env whose variable is set. Empty values are
+// ignored, as .env files often leave variables blank, except for lists and maps: these
+// are comma-separated ("a,b" or "k=v,k=v") and an empty value clears them.
+func applyEnv(cfg *Config) error {
+	err := walk(reflect.ValueOf(cfg).Elem(), func(field reflect.Value, tag reflect.StructField) error {
+		name := tag.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok || (strings.TrimSpace(value) == "" && field.Kind() != reflect.Slice && field.Kind() != reflect.Map) {
+			return nil
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return applyProviderEnv(&cfg.OAuth)
+}
+
+// applyProviderEnv reads OAuth providers from variables named OAUTH_<NAME>_<KEY>,
+// where KEY is a provider setting such as CLIENT_ID and NAME becomes the lowercase
+// provider name, e.g. OAUTH_LINUXDO_CLIENT_ID
+func applyProviderEnv(cfg *OAuthConfig) error {
+	providerType := reflect.TypeOf(OAuthProviderConfig{})
+
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, "OAUTH_") || strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		for i := 0; i < providerType.NumField(); i++ {
+			setting, _, _ := strings.Cut(providerType.Field(i).Tag.Get("yaml"), ",")
+			name, ok := strings.CutSuffix(strings.TrimPrefix(key, "OAUTH_"), "_"+strings.ToUpper(setting))
+			if !ok || name == "" {
+				continue
+			}
+
+			name = strings.ToLower(name)
+			if cfg.Providers == nil {
+				cfg.Providers = map[string]OAuthProviderConfig{}
+			}
+			provider := cfg.Providers[name]
+			if err := setField(reflect.ValueOf(&provider).Elem().Field(i), value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			cfg.Providers[name] = provider
+			break
+		}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets masked, safe to print
+func (c Config) Redacted() Config {
+	redacted := c
+	// 切片字段与原配置共享底层数组，但只有字符串类型的密钥会被改写，不影响原配置
+	_ = walk(reflect.ValueOf(&redacted).Elem(), func(field reflect.Value, tag reflect.StructField) error {
+		if tag.Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(redactedValue)
+		}
+		return nil
+	})
+
+	providers := make(map[string]OAuthProviderConfig, len(c.OAuth.Providers))
+	for name, provider := range c.OAuth.Providers {
+		if provider.ClientSecret != "" {
+			provider.ClientSecret = redactedValue
+		}
+		providers[name] = provider
+	}
+	redacted.OAuth.Providers = providers
+	return redacted
+}
+
+// walk calls fn for every leaf field of the nested config structs
+func walk(v reflect.Value, fn func(field reflect.Value, tag reflect.StructField) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, tag := v.Field(i), v.Type().Field(i)
+		if field.Kind() == reflect.Struct && tag.Tag.Get("env") == "" {
+			if err := walk(field, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField parses value into field according to the field's type
+func setField(field reflect.Value, value string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.String:
+		field.SetString(strings.TrimSpace(value))
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		items := map[string]string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid entry %q, expected key=value", item)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -1,36 +1,66 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"giftredeem/internal/models"
-	"os"
+	"giftredeem/internal/tracing"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// ErrNotMigrated indicates a table of the schema is missing
+var ErrNotMigrated = errors.New("database migrations not applied")
+
+// migratedModels are the models whose tables AutoMigrate manages
+var migratedModels = []interface{}{
+	&models.User{},
+	&models.OAuthAccount{},
+	&models.OAuthProvider{},
+	&models.Benefit{},
+	&models.RedemptionCode{},
+	&models.Claim{},
+	&models.ClaimItem{},
+	&models.CreatorBan{},
+	&models.AuditEvent{},
+	&models.WebhookEndpoint{},
+	&models.WebhookDelivery{},
+	&models.OutboxEvent{},
+	&models.OutboxConsumption{},
+	&models.NotificationPreference{},
+	&models.EmailNotification{},
+	&models.Notification{},
+	&models.RateLimitBucket{},
+	&models.UsedChallenge{},
+}
+
+// Config holds the database connection parameters
+type Config struct {
+	Username string
+	Password string
+	Host     string
+	Port     int
+	Name     string
+}
+
 // Initialize sets up the database connection and performs migrations
-func Initialize() error {
-	// Database connection parameters
-	username := getEnv("DB_USERNAME", "root")
-	password := getEnv("DB_PASSWORD", "")
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "3306")
-	dbName := getEnv("DB_NAME", "giftredeem")
+func Initialize(cfg Config) error {
 	charset := "utf8mb4"
 	loc := "Local"
 
 	// Build DSN (Data Source Name)
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=%s",
-		username, password, host, port, dbName, charset, loc)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=%s",
+		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Name, charset, loc)
 
 	// Configure GORM
 	config := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormLogger{},
 	}
 
 	// Connect to the database
@@ -40,6 +70,11 @@ func Initialize() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Trace queries that run with a traced context
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	// Configure connection pool
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -63,14 +98,7 @@ func Initialize() error {
 // runMigrations performs database schema migrations
 func runMigrations() error {
 	// AutoMigrate will create tables, missing foreign keys, constraints, columns and indexes
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.OAuthAccount{},
-		&models.OAuthProvider{},
-		&models.Benefit{},
-		&models.RedemptionCode{},
-		&models.Claim{},
-	)
+	err := DB.AutoMigrate(migratedModels...)
 	if err != nil {
 		return err
 	}
@@ -93,26 +121,56 @@ func runMigrations() error {
 			name: "修改 oauth_providers.client_secret 为可为空",
 			sql:  "ALTER TABLE oauth_providers MODIFY COLUMN client_secret TEXT NULL;",
 		},
+		{
+			name: "删除 claims 上旧的 (user_id, benefit_id) 唯一索引",
+			sql:  "ALTER TABLE claims DROP INDEX idx_user_benefit;",
+		},
+		{
+			name: "为历史领取记录补充 claim_items",
+			sql:  "INSERT INTO claim_items (claim_id, code_id, created_at) SELECT c.id, c.code_id, c.claimed_at FROM claims c LEFT JOIN claim_items ci ON ci.claim_id = c.id WHERE ci.id IS NULL AND c.code_id > 0;",
+		},
 	}
 
 	// 执行所有迁移
 	for _, migration := range migrations {
-		err = DB.Exec(migration.sql).Error
+		// 预期中的失败不必作为查询错误记录
+		err = DB.Session(&gorm.Session{Logger: gormlogger.Discard}).Exec(migration.sql).Error
 		if err != nil {
-			fmt.Printf("注意: %s 时出现错误（可能字段已经是 NULL）: %v\n", migration.name, err)
+			// 字段已经是 NULL 或索引已删除时会出错，可以忽略
+			logger.Info("custom migration skipped", "migration", migration.name, "error", err)
 		} else {
-			fmt.Printf("成功: %s\n", migration.name)
+			logger.Info("custom migration applied", "migration", migration.name)
 		}
 	}
 
 	return nil
 }
 
-// getEnv retrieves an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// Ping checks that the database is reachable
+func Ping(ctx context.Context) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations reports ErrNotMigrated when a table of the schema is missing
+func CheckMigrations(ctx context.Context) error {
+	migrator := DB.WithContext(ctx).Migrator()
+	for _, model := range migratedModels {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("%w: missing table for %T", ErrNotMigrated, model)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection pool
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
-	return value
+	return sqlDB.Close()
 }
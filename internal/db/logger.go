@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"giftredeem/internal/logging"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which statements are logged as warnings
+const slowQueryThreshold = 200 * time.Millisecond
+
+var logger = logging.For("db")
+
+// gormLogger writes GORM's logs to the "db" subsystem logger. Statements are logged
+// at debug level and slow ones as warnings; bound parameters are never logged, as
+// they include redemption codes and tokens.
+type gormLogger struct{}
+
+func (gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	// 级别由 logging 按子系统配置
+	return gormLogger{}
+}
+
+func (gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "elapsed", elapsed, "error", err)
+	case elapsed > slowQueryThreshold:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "elapsed", elapsed)
+	case logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		logger.DebugContext(ctx, "query", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
+
+// ParamsFilter keeps bound parameters out of the logged SQL
+func (gormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
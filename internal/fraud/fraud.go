@@ -0,0 +1,166 @@
+// Package fraud scores claim attempts by how much they look like one person farming
+// a benefit with several accounts. The score is the sum of the points of every signal
+// that fired, capped at MaxScore; creators decide what a given score leads to.
+package fraud
+
+import (
+	"fmt"
+	"giftredeem/internal/models"
+	"net"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxScore is the highest score an attempt can get
+const MaxScore = 100
+
+// Signals that contribute to a score
+const (
+	SignalSameIP        = "same_ip"
+	SignalSameSubnet    = "same_subnet"
+	SignalSameUserAgent = "same_user_agent"
+	SignalFreshAccount  = "fresh_account"
+	SignalBurst         = "burst"
+)
+
+// Thresholds and points of the individual signals
+const (
+	sameIPPoints      = 30 // 同一 IP 上已有其他账号领取
+	sameIPExtraPoints = 10 // 每多一个账号追加的分数
+	sameIPMaxPoints   = 60
+
+	sameSubnetPoints = 20 // 同一网段（IPv4 /24、IPv6 /64）上已有两个以上其他账号
+
+	sameUserAgentPoints = 15 // 短时间内多个账号使用完全相同的 User-Agent
+	sameUserAgentMin    = 3
+	sameUserAgentWindow = 10 * time.Minute
+
+	freshAccountPoints = 25 // 账号注册不足一天
+	youngAccountPoints = 10 // 账号注册不足一周
+
+	burstPoints = 15 // 该福利在最近一分钟内收到大量领取
+	burstMin    = 20
+	burstWindow = time.Minute
+)
+
+// Attempt describes a claim about to be made
+type Attempt struct {
+	BenefitID        uint
+	UserID           uint
+	IP               string
+	UserAgent        string
+	AccountCreatedAt time.Time
+	Now              time.Time
+}
+
+// Assessment is the outcome of scoring an attempt
+type Assessment struct {
+	Score   int
+	Reasons models.RiskReasons
+}
+
+// add records a signal and its points
+func (a *Assessment) add(signal string, points int, detail string) {
+	a.Reasons = append(a.Reasons, models.RiskReason{Signal: signal, Points: points, Detail: detail})
+	a.Score += points
+	if a.Score > MaxScore {
+		a.Score = MaxScore
+	}
+}
+
+// Score evaluates an attempt against the earlier claims on the same benefit. It only
+// reads, so it can run inside the claim transaction that holds the benefit lock.
+func Score(tx *gorm.DB, attempt Attempt) (Assessment, error) {
+	var result Assessment
+	if attempt.Now.IsZero() {
+		attempt.Now = time.Now()
+	}
+
+	// Other accounts that claimed from the same address
+	if attempt.IP != "" {
+		accounts, err := countAccounts(tx, attempt, "ip_address = ?", attempt.IP)
+		if err != nil {
+			return result, err
+		}
+		if accounts > 0 {
+			points := sameIPPoints + sameIPExtraPoints*(accounts-1)
+			if points > sameIPMaxPoints {
+				points = sameIPMaxPoints
+			}
+			result.add(SignalSameIP, points, fmt.Sprintf("%d other account(s) claimed from %s", accounts, attempt.IP))
+		}
+	}
+
+	// Other accounts that claimed from the same network, excluding the address itself
+	if subnet := SubnetOf(attempt.IP); subnet != "" {
+		accounts, err := countAccounts(tx, attempt, "subnet = ? AND ip_address <> ?", subnet, attempt.IP)
+		if err != nil {
+			return result, err
+		}
+		if accounts >= 2 {
+			result.add(SignalSameSubnet, sameSubnetPoints, fmt.Sprintf("%d other account(s) claimed from %s", accounts, subnet))
+		}
+	}
+
+	// Identical user agents within a short window
+	if attempt.UserAgent != "" {
+		accounts, err := countAccounts(tx, attempt, "user_agent = ? AND claimed_at >= ?", attempt.UserAgent, attempt.Now.Add(-sameUserAgentWindow))
+		if err != nil {
+			return result, err
+		}
+		if accounts >= sameUserAgentMin {
+			result.add(SignalSameUserAgent, sameUserAgentPoints, fmt.Sprintf("%d other account(s) used the same user agent in the last %s", accounts, sameUserAgentWindow))
+		}
+	}
+
+	// Freshly registered accounts
+	if !attempt.AccountCreatedAt.IsZero() {
+		age := attempt.Now.Sub(attempt.AccountCreatedAt)
+		switch {
+		case age < 24*time.Hour:
+			result.add(SignalFreshAccount, freshAccountPoints, "account registered less than a day ago")
+		case age < 7*24*time.Hour:
+			result.add(SignalFreshAccount, youngAccountPoints, "account registered less than a week ago")
+		}
+	}
+
+	// Claims arriving in a burst
+	var recent int64
+	err := tx.Model(&models.Claim{}).
+		Where("benefit_id = ? AND claimed_at >= ?", attempt.BenefitID, attempt.Now.Add(-burstWindow)).
+		Count(&recent).Error
+	if err != nil {
+		return result, err
+	}
+	if recent >= burstMin {
+		result.add(SignalBurst, burstPoints, fmt.Sprintf("%d claims on this benefit in the last %s", recent, burstWindow))
+	}
+
+	return result, nil
+}
+
+// countAccounts counts the other accounts whose claims on the benefit match the condition
+func countAccounts(tx *gorm.DB, attempt Attempt, query string, args ...interface{}) (int, error) {
+	var count int64
+	err := tx.Model(&models.Claim{}).
+		Where("benefit_id = ? AND user_id <> ?", attempt.BenefitID, attempt.UserID).
+		Where(query, args...).
+		Distinct("user_id").
+		Count(&count).Error
+	return int(count), err
+}
+
+// SubnetOf returns the network an address belongs to for scoring purposes: the /24
+// of an IPv4 address or the /64 of an IPv6 address. It returns "" for invalid input.
+func SubnetOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
@@ -0,0 +1,196 @@
+// Package frontend serves the built Vue application, either embedded in the binary
+// or from a directory on disk. Hashed build assets are cached forever, index.html is
+// revalidated on every load, precompressed .br and .gz variants are served to clients
+// that accept them, and unknown paths fall back to index.html so that client-side
+// routes survive a reload.
+package frontend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IndexFile is the entry point of the application
+const IndexFile = "index.html"
+
+// DefaultDir is where the build output is looked up when it is not embedded
+const DefaultDir = "./frontend/dist"
+
+// Cache-Control values
+const (
+	// Vite 构建产物的文件名包含内容哈希，内容变化时文件名随之变化
+	CacheImmutable   = "public, max-age=31536000, immutable"
+	CacheRevalidate  = "no-cache"
+	hashedAssetsPath = "assets/"
+)
+
+// ErrNoIndex indicates that the assets do not contain index.html, e.g. because the
+// frontend has not been built
+var ErrNoIndex = errors.New("frontend assets have no " + IndexFile)
+
+// encodings are the precompressed variants looked up, in order of preference
+var encodings = []struct {
+	name      string // Content-Encoding
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Server serves the files of a built frontend
+type Server struct {
+	files fs.FS
+
+	etags sync.Map // 嵌入的文件没有修改时间，以内容哈希作为 ETag；内容不会变化，可以缓存
+}
+
+// New returns a server for files, the contents of the build output directory. It
+// fails when files has no index.html.
+func New(files fs.FS) (*Server, error) {
+	if _, err := fs.Stat(files, IndexFile); err != nil {
+		return nil, ErrNoIndex
+	}
+	return &Server{files: files}, nil
+}
+
+// ServeHTTP serves the file at the request path, or index.html for paths that do not
+// name a file. Missing hashed assets are answered with 404 rather than index.html,
+// as browsers would otherwise try to run the page as a script.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = IndexFile
+	}
+
+	if s.isFile(name) {
+		s.serveFile(w, r, name)
+		return
+	}
+	if strings.HasPrefix(name, hashedAssetsPath) {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveFile(w, r, IndexFile)
+}
+
+// Index returns the contents of index.html
+func (s *Server) Index() ([]byte, error) {
+	return fs.ReadFile(s.files, IndexFile)
+}
+
+func (s *Server) isFile(name string) bool {
+	info, err := fs.Stat(s.files, name)
+	return err == nil && !info.IsDir()
+}
+
+// serveFile writes name, or its best precompressed variant accepted by the client
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	header := w.Header()
+	if strings.HasPrefix(name, hashedAssetsPath) {
+		header.Set("Cache-Control", CacheImmutable)
+	} else {
+		header.Set("Cache-Control", CacheRevalidate)
+	}
+
+	// Content-Type 取决于原始文件，而不是 .br/.gz
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+
+	served, varies := name, false
+	for _, encoding := range encodings {
+		if !s.isFile(name + encoding.extension) {
+			continue
+		}
+		varies = true
+		if accepts(r.Header.Get("Accept-Encoding"), encoding.name) {
+			served = name + encoding.extension
+			header.Set("Content-Encoding", encoding.name)
+			break
+		}
+	}
+	// 存在压缩版本时，响应内容随 Accept-Encoding 变化
+	if varies {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	f, err := s.files.Open(served)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		etag, err := s.etag(served, content)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		header.Set("ETag", etag)
+	}
+	http.ServeContent(w, r, served, modTime, content)
+}
+
+// etag returns the ETag of an embedded file, hashing its content on first use
+func (s *Server) etag(name string, content io.ReadSeeker) (string, error) {
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := strconv.Quote(hex.EncodeToString(hash.Sum(nil)[:16]))
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+// accepts reports whether an Accept-Encoding header allows encoding
+func accepts(acceptEncoding, encoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) && strings.TrimSpace(name) != "*" {
+			continue
+		}
+		// q=0 表示明确拒绝
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package frontend
+
+import (
+	"bytes"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Meta describes a page for link previews (Open Graph and Twitter cards)
+type Meta struct {
+	Title       string
+	Description string
+	URL         string // 页面的规范地址
+	SiteName    string
+	Labels      []Label // 摘要卡片中额外展示的键值，如剩余数量
+	NoIndex     bool    // 禁止搜索引擎收录
+}
+
+// Label is a key-value pair shown by Slack, Twitter and others below the description
+type Label struct {
+	Name  string
+	Value string
+}
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title>.*?</title>`)
+	headEnd      = []byte("</head>")
+)
+
+// ServeIndex writes index.html with the <title> replaced and meta tags describing the
+// page added to its head. The page is revalidated on every load, as it reflects the
+// current state of what it describes.
+func (s *Server) ServeIndex(w http.ResponseWriter, r *http.Request, meta Meta) {
+	index, err := s.Index()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", CacheRevalidate)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(RenderMeta(index, meta))
+	}
+}
+
+// RenderMeta returns index with meta applied. Preview tags are only added for pages
+// with a title. Every value is HTML-escaped.
+func RenderMeta(index []byte, meta Meta) []byte {
+	var tags strings.Builder
+	tag := func(attr, key, value string) {
+		if value == "" {
+			return
+		}
+		tags.WriteString(`    <meta ` + attr + `="` + html.EscapeString(key) + `" content="` + html.EscapeString(value) + `">` + "\n")
+	}
+
+	tag("name", "description", meta.Description)
+	if meta.Title != "" {
+		tag("property", "og:type", "website")
+		tag("property", "og:site_name", meta.SiteName)
+		tag("property", "og:title", meta.Title)
+		tag("property", "og:description", meta.Description)
+		tag("property", "og:url", meta.URL)
+		tag("name", "twitter:card", "summary")
+		tag("name", "twitter:title", meta.Title)
+		tag("name", "twitter:description", meta.Description)
+		for i, label := range meta.Labels {
+			n := strconv.Itoa(i + 1)
+			tag("name", "twitter:label"+n, label.Name)
+			tag("name", "twitter:data"+n, label.Value)
+		}
+	}
+	if meta.NoIndex {
+		tag("name", "robots", "noindex")
+	}
+
+	out := index
+	if meta.Title != "" {
+		title := []byte("<title>" + html.EscapeString(meta.Title) + "</title>")
+		if titlePattern.Match(out) {
+			out = titlePattern.ReplaceAllLiteral(out, title)
+		} else {
+			tags.WriteString("    " + string(title) + "\n")
+		}
+	}
+
+	i := bytes.Index(bytes.ToLower(out), headEnd)
+	if i < 0 {
+		// 没有 </head> 时浏览器仍会把开头的 meta 放进 head
+		return append([]byte(tags.String()), out...)
+	}
+	rendered := make([]byte, 0, len(out)+tags.Len())
+	rendered = append(rendered, out[:i]...)
+	rendered = append(rendered, tags.String()...)
+	return append(rendered, out[i:]...)
+}
@@ -0,0 +1,208 @@
+package live
+
+import (
+	"context"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"sync"
+	"time"
+)
+
+var logger = logging.For("live")
+
+// Update is the live state of a benefit pushed to watchers
+type Update struct {
+	UUID         string `json:"uuid"`
+	ClaimedCount int    `json:"claimed_count"`
+	TotalCount   int    `json:"total_count"`
+	Status       string `json:"status"`
+}
+
+// Hub fans benefit updates out to watchers. A single poller reads the state of
+// every watched benefit in one query per interval, so the database load does not
+// grow with the number of connected clients.
+type Hub struct {
+	mu       sync.Mutex
+	topics   map[string]*topic // 以福利 UUID 为键
+	interval time.Duration
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// topic holds the watchers of one benefit and the last state sent to them
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	last        Update
+}
+
+// Subscription receives the updates of one benefit. Each subscription buffers a
+// single update: when a slow client has not consumed the previous one yet, it is
+// replaced by the newer state, so a stalled connection never blocks the hub.
+type Subscription struct {
+	C    <-chan Update
+	ch   chan Update
+	uuid string
+	hub  *Hub
+}
+
+// NewHub creates a hub polling the database at the given interval
+func NewHub(interval time.Duration) *Hub {
+	return &Hub{
+		topics:   make(map[string]*topic),
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// DefaultHub is the hub used by the API
+var DefaultHub = NewHub(time.Second)
+
+// Subscribe starts watching a benefit. current is the state the caller has just read
+// and already sent; only later changes are delivered. It is normally newer than the
+// last polled state, so other watchers of the benefit are brought up to date with it.
+func (h *Hub) Subscribe(current Update) *Subscription {
+	ch := make(chan Update, 1)
+	sub := &Subscription{C: ch, ch: ch, uuid: current.UUID, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[current.UUID]
+	if !ok {
+		t = &topic{subscribers: make(map[*Subscription]struct{}), last: current}
+		h.topics[current.UUID] = t
+	} else if t.last != current {
+		// 新订阅者刚从数据库读到的状态比上次轮询的结果更新，同步给已有的订阅者；
+		// 若轮询恰好在此之后读到了更新的状态，下一次轮询会发现差异并重新推送
+		t.last = current
+		t.broadcast(current)
+	}
+	t.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Done is closed when the hub shuts down; watchers should then end their streams
+func (s *Subscription) Done() <-chan struct{} {
+	return s.hub.done
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	t, ok := s.hub.topics[s.uuid]
+	if !ok {
+		return
+	}
+	delete(t.subscribers, s)
+	if len(t.subscribers) == 0 {
+		delete(s.hub.topics, s.uuid)
+	}
+}
+
+// Shutdown tells every watcher to disconnect, so that long-lived streams do not hold
+// up a graceful server shutdown. It is safe to call more than once.
+func (h *Hub) Shutdown() {
+	h.stopOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// Publish sends u to the watchers of its benefit if it differs from the last state sent
+func (h *Hub) Publish(u Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[u.UUID]
+	if !ok || t.last == u {
+		return
+	}
+	t.last = u
+	t.broadcast(u)
+}
+
+// broadcast sends u to every subscriber of the topic; the hub lock must be held
+func (t *topic) broadcast(u Update) {
+	for sub := range t.subscribers {
+		select {
+		case sub.ch <- u:
+		default:
+			// 客户端尚未读取上一条更新，丢弃旧状态，只保留最新状态
+			select {
+			case <-sub.ch:
+			default:
+			}
+			select {
+			case sub.ch <- u:
+			default:
+			}
+		}
+	}
+}
+
+// Watched returns the number of benefits currently watched
+func (h *Hub) Watched() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics)
+}
+
+// Run polls the watched benefits until ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := h.poll(ctx); err != nil {
+			logger.Error("failed to poll benefits", "error", err)
+		}
+	}
+}
+
+// poll reads the current state of every watched benefit and publishes changes
+func (h *Hub) poll(ctx context.Context) error {
+	h.mu.Lock()
+	uuids := make([]string, 0, len(h.topics))
+	for uuid := range h.topics {
+		uuids = append(uuids, uuid)
+	}
+	h.mu.Unlock()
+
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	var benefits []models.Benefit
+	err := db.DB.WithContext(ctx).
+		Select("uuid", "claimed_count", "total_count", "status").
+		Where("uuid IN ?", uuids).
+		Find(&benefits).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range benefits {
+		h.Publish(FromBenefit(&benefits[i]))
+	}
+
+	return nil
+}
+
+// FromBenefit builds the live state of a benefit
+func FromBenefit(benefit *models.Benefit) Update {
+	return Update{
+		UUID:         benefit.UUID,
+		ClaimedCount: benefit.ClaimedCount,
+		TotalCount:   benefit.TotalCount,
+		Status:       benefit.Status,
+	}
+}
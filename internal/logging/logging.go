@@ -0,0 +1,181 @@
+// Package logging provides the structured loggers of the server, built on log/slog.
+// Each subsystem gets its own logger, whose level can be configured separately;
+// records carry the request ID and trace ID of the context they are logged with, and
+// attributes holding tokens, codes or secrets are redacted before they are written.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Output formats accepted by Config.Format
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config describes how logs are written
+type Config struct {
+	Level  string            // 默认级别：debug/info/warn/error
+	Format string            // text/json
+	Levels map[string]string // 按子系统覆盖级别，如 {"db": "warn", "auth": "debug"}
+}
+
+var (
+	base atomic.Pointer[slog.Handler]
+
+	mu           sync.RWMutex
+	defaultLevel = slog.LevelInfo
+	levels       = map[string]slog.Level{}
+)
+
+func init() {
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr})
+	base.Store(&h)
+}
+
+// Validate checks the levels and the format
+func (c Config) Validate() error {
+	_, _, err := c.levels()
+	if err == nil && c.Format != "" && c.Format != FormatText && c.Format != FormatJSON {
+		err = fmt.Errorf("unknown log format %q, use %s or %s", c.Format, FormatText, FormatJSON)
+	}
+	return err
+}
+
+// levels parses the default level and the per-subsystem overrides
+func (c Config) levels() (slog.Level, map[string]slog.Level, error) {
+	level, err := ParseLevel(c.Level)
+	if err != nil {
+		return 0, nil, err
+	}
+	overrides := make(map[string]slog.Level, len(c.Levels))
+	for subsystem, value := range c.Levels {
+		l, err := ParseLevel(value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", subsystem, err)
+		}
+		overrides[subsystem] = l
+	}
+	return level, overrides, nil
+}
+
+// Setup applies cfg to every logger, including those created before the call, and
+// routes the standard library logger and slog.Default through it
+func Setup(cfg Config, w io.Writer) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	level, overrides, _ := cfg.levels()
+
+	// 级别由子系统的 handler 判断，这里放行所有记录
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}
+	var h slog.Handler = slog.NewTextHandler(w, opts)
+	if cfg.Format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	}
+
+	mu.Lock()
+	defaultLevel = level
+	levels = overrides
+	mu.Unlock()
+	base.Store(&h)
+
+	slog.SetDefault(For("app"))
+	log.SetFlags(0)
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error; an empty string means info
+func ParseLevel(value string) (slog.Level, error) {
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(value))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", value)
+	}
+	return level, nil
+}
+
+// For returns the logger of a subsystem. Records carry a "subsystem" attribute and
+// are filtered by the subsystem's configured level.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem}).With("subsystem", subsystem)
+}
+
+// levelOf returns the configured level of a subsystem
+func levelOf(subsystem string) slog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	if level, ok := levels[subsystem]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+// handler filters records by the level of its subsystem and writes them to the
+// current base handler, so that loggers created at package initialisation follow
+// the configuration applied later by Setup
+type handler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler // WithAttrs/WithGroup 调用，按顺序作用在基础 handler 上
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= levelOf(h.subsystem)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	inner := *base.Load()
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	return inner.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{subsystem: h.subsystem, ops: append(ops, op)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the current request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
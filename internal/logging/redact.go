@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces sensitive values in logs
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute, header, form and query names whose values are never logged
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"jwt":           true,
+	"code":          true,
+	"codes":         true,
+	"state":         true,
+	"secret":        true,
+	"client_secret": true,
+	"password":      true,
+	"authorization": true,
+	"cookie":        true,
+	"set_cookie":    true,
+	"api_key":       true,
+}
+
+// IsSensitive reports whether values named key must be redacted
+func IsSensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range []string{"_token", "_secret", "_password", "_code"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL masks the values of sensitive query parameters, e.g. the token in a
+// login redirect. Values that do not parse as URLs are returned unchanged.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	u.RawQuery = redactValues(u.Query()).Encode()
+	return u.String()
+}
+
+// redactAttr is the ReplaceAttr hook of every handler
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch v := a.Value.Any().(type) {
+	case string:
+		if strings.HasPrefix(strings.ToLower(v), "bearer ") {
+			return slog.String(a.Key, Redacted)
+		}
+	case http.Header:
+		return slog.Any(a.Key, redactHeader(v))
+	case url.Values:
+		return slog.Any(a.Key, redactValues(v))
+	case map[string]string:
+		return slog.Any(a.Key, redactMap(v))
+	case map[string]interface{}:
+		return slog.Any(a.Key, redactMap(v))
+	case []*http.Cookie:
+		names := make([]string, len(v))
+		for i, cookie := range v {
+			names[i] = cookie.Name + "=" + Redacted
+		}
+		return slog.Any(a.Key, names)
+	}
+	return a
+}
+
+func redactHeader(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for key, values := range h {
+		if IsSensitive(key) {
+			values = []string{Redacted}
+		}
+		redacted[key] = values
+	}
+	return redacted
+}
+
+func redactValues(values url.Values) url.Values {
+	redacted := make(url.Values, len(values))
+	for key, v := range values {
+		if IsSensitive(key) {
+			v = []string{Redacted}
+		}
+		redacted[key] = v
+	}
+	return redacted
+}
+
+func redactMap[V any](m map[string]V) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if IsSensitive(key) {
+			redacted[key] = Redacted
+			continue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
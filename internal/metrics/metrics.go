@@ -0,0 +1,90 @@
+// Package metrics defines the Prometheus metrics of the server. They are registered
+// with the default registry and served on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "giftredeem"
+
+// Values of the result label
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// UnknownProvider labels requests for providers that do not exist, so that arbitrary
+// names in the URL cannot create new series
+const UnknownProvider = "unknown"
+
+var (
+	// HTTPRequestDuration observes request latency per route template
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// ClaimsTotal counts claim attempts by outcome: "success", "pending_review" or
+	// the name of the benefit package error, e.g. "ErrNoCodeAvailable"
+	ClaimsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "claims_total",
+		Help:      "Claim attempts by outcome.",
+	}, []string{"outcome"})
+
+	// OAuthLoginsTotal counts OAuth logins per provider and result
+	OAuthLoginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "oauth_logins_total",
+		Help:      "OAuth logins by provider and result.",
+	}, []string{"provider", "result"})
+
+	// OAuthTokenExchangeDuration observes the latency of the provider's token endpoint
+	OAuthTokenExchangeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "oauth_token_exchange_duration_seconds",
+		Help:      "Duration of OAuth authorization code exchanges by provider and result.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"provider", "result"})
+)
+
+// ObserveRequest records a finished HTTP request
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	HTTPRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// ObserveClaim records the outcome of a claim attempt
+func ObserveClaim(outcome string) {
+	ClaimsTotal.WithLabelValues(outcome).Inc()
+}
+
+// ObserveLogin records an OAuth login attempt
+func ObserveLogin(provider string, success bool) {
+	OAuthLoginsTotal.WithLabelValues(provider, result(success)).Inc()
+}
+
+// ObserveTokenExchange records a code exchange that started at start
+func ObserveTokenExchange(provider string, start time.Time, success bool) {
+	OAuthTokenExchangeDuration.WithLabelValues(provider, result(success)).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDB exports the connection pool statistics of sqlDB (sqlDB.Stats())
+func RegisterDB(sqlDB *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+func result(success bool) string {
+	if success {
+		return ResultSuccess
+	}
+	return ResultFailure
+}
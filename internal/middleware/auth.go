@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"giftredeem/internal/auth"
+	"giftredeem/internal/models"
 	"giftredeem/internal/response"
 	"net/http"
 	"strings"
@@ -87,3 +88,23 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware only lets administrators through; it must run after AuthMiddleware
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userValue, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusOK, response.Error(response.CodeUnauthorized, "User not authenticated"))
+			c.Abort()
+			return
+		}
+
+		if user, ok := userValue.(*models.User); !ok || user.Role != "admin" {
+			c.JSON(http.StatusOK, response.Error(response.CodeForbidden, "Administrator privileges required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
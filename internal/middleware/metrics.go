@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"giftredeem/internal/metrics"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that matched no route, keeping arbitrary paths out of the labels
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records the duration of every request, labeled with the route
+// template (e.g. /api/claim/:uuid) rather than the concrete path
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsHandler serves the Prometheus metrics. When token is non-empty, scrapers
+// must send it as a bearer token.
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := promhttp.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"giftredeem/internal/clientip"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyMiddleware replaces the peer address, scheme and host of the request with
+// what the client sent, as reported by trusted proxies. It must run before anything
+// reading c.ClientIP(), c.Request.Host or c.Request.URL.Scheme, and gin's own proxy
+// handling must be disabled so the rewritten address is used as is.
+func ProxyMiddleware(resolver *clientip.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := c.Request
+		scheme := resolver.Scheme(req)
+		host := resolver.Host(req)
+		if ip := resolver.ClientIP(req); ip != "" {
+			req.RemoteAddr = net.JoinHostPort(ip, "0")
+		}
+		req.URL.Scheme = scheme
+		req.Host = host
+		c.Next()
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"giftredeem/internal/ratelimit"
+	"giftredeem/internal/response"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var rateLimitLogger = logging.For("ratelimit")
+
+// RateLimitKey extracts the value a rule is keyed by; an empty key skips the rule
+type RateLimitKey func(c *gin.Context) string
+
+// RateLimitRule limits requests sharing the same key
+type RateLimitRule struct {
+	Name  string // 用于区分不同规则的桶，如 "claim:user"
+	Key   RateLimitKey
+	Limit ratelimit.Limit
+}
+
+// ByIP keys a rule by client IP
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByUser keys a rule by the authenticated user; it must run after AuthMiddleware
+func ByUser(c *gin.Context) string {
+	userValue, exists := c.Get("user")
+	if !exists {
+		return ""
+	}
+	if user, ok := userValue.(*models.User); ok {
+		return strconv.FormatUint(uint64(user.ID), 10)
+	}
+	return ""
+}
+
+// ByBenefit keys a rule by the benefit UUID in the path, limiting all requests to one benefit together
+func ByBenefit(c *gin.Context) string {
+	return c.Param("uuid")
+}
+
+// RateLimitMiddleware rejects requests exceeding any of the rules with HTTP 429,
+// a Retry-After header and CodeRateLimited. If the store fails, requests are let
+// through rather than taking the API down with it.
+func RateLimitMiddleware(store ratelimit.Store, rules ...RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, rule := range rules {
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+
+			result, err := store.Take(c.Request.Context(), rule.Name+":"+key, rule.Limit)
+			if err != nil {
+				rateLimitLogger.ErrorContext(c.Request.Context(), "failed to take token", "rule", rule.Name, "error", err)
+				continue
+			}
+
+			if !result.Allowed {
+				tooManyRequests(c, result.RetryAfter)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// concurrencyRetryAfter is the delay suggested to clients over the concurrency limit
+const concurrencyRetryAfter = 15 * time.Second
+
+// ConcurrencyLimitMiddleware rejects requests with HTTP 429 while max requests with the
+// same key are in flight on this instance. Token buckets only limit how often
+// long-lived requests such as streams are opened, not how many stay open.
+func ConcurrencyLimitMiddleware(key RateLimitKey, max int) gin.HandlerFunc {
+	var mu sync.Mutex
+	active := make(map[string]int)
+
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		mu.Lock()
+		if active[k] >= max {
+			mu.Unlock()
+			tooManyRequests(c, concurrencyRetryAfter)
+			return
+		}
+		active[k]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if active[k]--; active[k] <= 0 {
+				delete(active, k)
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// tooManyRequests aborts the request with HTTP 429 and a Retry-After header
+func tooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, response.Error(response.CodeRateLimited, "Too many requests, please retry later"))
+}
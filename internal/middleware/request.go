@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"giftredeem/internal/audit"
+	"giftredeem/internal/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients and proxies
+const maxRequestIDLength = 64
+
+var accessLogger = logging.For("http")
+
+// RequestContextMiddleware attaches the client IP and user agent to the request context
+// so that services can enrich audit events without depending on gin
+func RequestContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithRequest(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequestIDMiddleware gives every request an ID, reusing a well-formed X-Request-ID
+// set by a proxy. The ID is echoed back in the response and carried by the request
+// context, so that every log record of the request can be correlated.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		// 在 trace 中也能按请求 ID 查找
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("request.id", id))
+		c.Next()
+	}
+}
+
+// AccessLogMiddleware logs every request once it has been handled. Sensitive query
+// parameters such as tokens and authorization codes are redacted.
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		log := accessLogger.InfoContext
+		if status >= 500 {
+			log = accessLogger.ErrorContext
+		}
+		log(c.Request.Context(), "request",
+			"method", c.Request.Method,
+			"path", logging.RedactURL(c.Request.URL.RequestURI()),
+			"route", c.FullPath(),
+			"status", status,
+			"elapsed", time.Since(start),
+			"ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// validRequestID accepts short IDs made of letters, digits, dashes and underscores
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditEventImmutable is returned when something tries to modify a stored audit event
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent represents an append-only record of an action performed in the system
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"` // 为空表示系统或匿名操作
+	Action     string    `json:"action" gorm:"type:varchar(64);index"`
+	TargetType string    `json:"target_type" gorm:"type:varchar(32);index:idx_audit_target"`
+	TargetID   string    `json:"target_id" gorm:"type:varchar(64);index:idx_audit_target"`
+	BenefitID  *uint     `json:"benefit_id" gorm:"index"` // 相关福利，供发布者查询
+	Before     JSON      `json:"before" gorm:"type:json"`
+	After      JSON      `json:"after" gorm:"type:json"`
+	IPAddress  string    `json:"ip_address" gorm:"type:varchar(64)"`
+	UserAgent  string    `json:"user_agent" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// BeforeUpdate keeps audit events append-only
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+// BeforeDelete keeps audit events append-only
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
@@ -9,48 +9,181 @@ import (
 
 // Benefit represents a benefit with multiple redemption codes
 type Benefit struct {
-	ID               uint        `json:"id" gorm:"primaryKey"`
-	UUID             string      `json:"uuid" gorm:"type:varchar(255);uniqueIndex"` // For generating private links
-	Title            string      `json:"title"`
-	Description      string      `json:"description"`
-	CreatorID        uint        `json:"creator_id"`
-	Creator          User        `json:"-" gorm:"foreignKey:CreatorID"`
-	TotalCount       int         `json:"total_count"`
-	ClaimedCount     int         `json:"claimed_count"`
-	CreatedAt        time.Time   `json:"created_at"`
-	ExpiresAt        time.Time   `json:"expires_at"`
-	Status           string      `json:"status" gorm:"default:'active'"` // active/paused/expired/deleted
-	AllowedProviders StringSlice `json:"allowed_providers" gorm:"type:json"`
-	MinAccountAge    int         `json:"min_account_age"`
-	ClaimConditions  JSON        `json:"claim_conditions" gorm:"type:json"`
+	ID                  uint        `json:"id" gorm:"primaryKey"`
+	UUID                string      `json:"uuid" gorm:"type:varchar(255);uniqueIndex"` // For generating private links
+	Title               string      `json:"title"`
+	Description         string      `json:"description"`
+	CreatorID           uint        `json:"creator_id"`
+	Creator             User        `json:"-" gorm:"foreignKey:CreatorID"`
+	TotalCount          int         `json:"total_count"`
+	ClaimedCount        int         `json:"claimed_count"`
+	CreatedAt           time.Time   `json:"created_at"`
+	ExpiresAt           time.Time   `json:"expires_at"`
+	Status              string      `json:"status" gorm:"default:'active'"` // active/paused/expired/deleted
+	AllowedProviders    StringSlice `json:"allowed_providers" gorm:"type:json"`
+	MinAccountAge       int         `json:"min_account_age"`
+	ClaimConditions     JSON        `json:"claim_conditions" gorm:"type:json"`
+	PerUserQuota        int         `json:"per_user_quota" gorm:"default:1"`             // 每个用户最多可获得的兑换码总数
+	CodesPerClaim       int         `json:"codes_per_claim" gorm:"default:1"`            // 每次领取发放的兑换码数量
+	PeriodLimit         int         `json:"period_limit"`                                // 每个周期内的领取次数上限，0 表示不限制
+	LimitPeriod         string      `json:"limit_period"`                                // day/week/month，与 PeriodLimit 配合使用
+	AllocationMode      string      `json:"allocation_mode" gorm:"default:'sequential'"` // sequential/weighted
+	TierRules           TierRules   `json:"tier_rules" gorm:"type:json"`                 // 确定性分配规则，优先于 AllocationMode
+	CodeFormat          string      `json:"code_format"`                                 // 导入时使用的兑换码格式，见 codeformat 包
+	CodePattern         string      `json:"code_pattern"`                                // CodeFormat 为 regex 时的自定义正则
+	Challenge           string      `json:"challenge"`                                   // 领取前需要完成的挑战：空/pow/captcha
+	ChallengeDifficulty int         `json:"challenge_difficulty"`                        // pow 挑战的难度（前导零位数）
+	FraudThreshold      int         `json:"fraud_threshold"`                             // 风险分达到该值时执行 FraudAction，0 表示不启用
+	FraudAction         string      `json:"fraud_action"`                                // block/flag/review
+	Private             bool        `json:"private"`                                     // 私密福利不生成链接预览
+	ExpiringNoticeAt    *time.Time  `json:"-"`                                           // 已发出“即将过期”提醒的时间
 }
 
 // RedemptionCode represents a single code within a benefit
 type RedemptionCode struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	BenefitID uint       `json:"benefit_id"`
-	Benefit   Benefit    `json:"-" gorm:"foreignKey:BenefitID"`
-	Code      string     `json:"code"`
-	Status    string     `json:"status" gorm:"default:'available'"` // available/claimed/expired
-	ClaimedBy *uint      `json:"claimed_by"`                        // 使用指针类型，允许为NULL
-	User      User       `json:"-" gorm:"foreignKey:ClaimedBy"`
-	ClaimedAt *time.Time `json:"claimed_at"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	BenefitID  uint         `json:"benefit_id"`
+	Benefit    Benefit      `json:"-" gorm:"foreignKey:BenefitID"`
+	Code       string       `json:"code"`
+	Tier       string       `json:"tier" gorm:"type:varchar(64);index"` // 奖品档位名称，空字符串表示默认档位
+	TierWeight int          `json:"tier_weight"`                        // 加权随机分配时该档位的权重
+	TierValue  float64      `json:"tier_value"`                         // 该档位的价值，仅用于展示和统计
+	Metadata   CodeMetadata `json:"metadata" gorm:"embedded"`
+	Status     string       `json:"status" gorm:"default:'available'"` // available/claimed/expired/invalid/burned
+	ClaimedBy  *uint        `json:"claimed_by"`                        // 使用指针类型，允许为NULL
+	User       User         `json:"-" gorm:"foreignKey:ClaimedBy"`
+	ClaimedAt  *time.Time   `json:"claimed_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// CodeMetadata describes where and how a redemption code can be used
+type CodeMetadata struct {
+	Platform     string     `json:"platform" gorm:"type:varchar(64)"` // steam/psn/app_store/...
+	Region       string     `json:"region" gorm:"type:varchar(64)"`
+	FaceValue    string     `json:"face_value" gorm:"type:varchar(64)"`
+	ExpiresAt    *time.Time `json:"expires_at"` // 兑换码本身的过期时间，可能为空
+	Instructions string     `json:"instructions" gorm:"type:text"`
+}
+
+// Merge returns a copy of m with every empty field filled from defaults
+func (m CodeMetadata) Merge(defaults CodeMetadata) CodeMetadata {
+	if m.Platform == "" {
+		m.Platform = defaults.Platform
+	}
+	if m.Region == "" {
+		m.Region = defaults.Region
+	}
+	if m.FaceValue == "" {
+		m.FaceValue = defaults.FaceValue
+	}
+	if m.ExpiresAt == nil {
+		m.ExpiresAt = defaults.ExpiresAt
+	}
+	if m.Instructions == "" {
+		m.Instructions = defaults.Instructions
+	}
+	return m
 }
 
 // Claim represents a record of a user claiming a benefit
 type Claim struct {
 	ID             uint           `json:"id" gorm:"primaryKey"`
-	UserID         uint           `json:"user_id" gorm:"index:idx_user_benefit,unique:true"`
+	UserID         uint           `json:"user_id" gorm:"index:idx_claims_user_benefit"`
 	User           User           `json:"-" gorm:"foreignKey:UserID"`
-	BenefitID      uint           `json:"benefit_id" gorm:"index:idx_user_benefit,unique:true"`
+	BenefitID      uint           `json:"benefit_id" gorm:"index:idx_claims_user_benefit;index:idx_claims_benefit_ip,priority:1;index:idx_claims_benefit_subnet,priority:1"`
 	Benefit        Benefit        `json:"-" gorm:"foreignKey:BenefitID"`
-	CodeID         uint           `json:"code_id"`
+	CodeID         uint           `json:"code_id"` // 本次领取的第一个兑换码，完整列表见 Items
 	RedemptionCode RedemptionCode `json:"-" gorm:"foreignKey:CodeID"`
+	Items          []ClaimItem    `json:"items" gorm:"foreignKey:ClaimID"`
 	OAuthProvider  string         `json:"oauth_provider"`
 	ClaimedAt      time.Time      `json:"claimed_at"` // 这个字段始终有值，不需要是指针
-	IPAddress      string         `json:"ip_address"`
+	IPAddress      string         `json:"ip_address" gorm:"type:varchar(64);index:idx_claims_benefit_ip,priority:2"`
+	Subnet         string         `json:"-" gorm:"type:varchar(64);index:idx_claims_benefit_subnet,priority:2"` // IPv4 /24 或 IPv6 /64
 	UserAgent      string         `json:"user_agent"`
+	Status         string         `json:"status" gorm:"default:'active'"` // active/revoked
+	RevokedAt      *time.Time     `json:"revoked_at"`
+	RevokedBy      *uint          `json:"revoked_by"`
+	RevokeReason   string         `json:"revoke_reason" gorm:"type:text"`
+	RiskScore      int            `json:"risk_score"`
+	RiskReasons    RiskReasons    `json:"risk_reasons" gorm:"type:json"`
+	ReviewStatus   string         `json:"review_status" gorm:"type:varchar(16)"` // 空/flagged/pending/approved/rejected
+	ReviewedAt     *time.Time     `json:"reviewed_at"`
+	ReviewedBy     *uint          `json:"reviewed_by"`
+}
+
+// ClaimItem represents a single redemption code handed out as part of a claim
+type ClaimItem struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	ClaimID        uint           `json:"claim_id" gorm:"index"`
+	CodeID         uint           `json:"code_id" gorm:"index"`
+	RedemptionCode RedemptionCode `json:"-" gorm:"foreignKey:CodeID"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Feedback       string         `json:"feedback" gorm:"type:varchar(32)"` // 领取者反馈：redeemed/invalid/already_used
+	FeedbackNote   string         `json:"feedback_note" gorm:"type:text"`
+	FeedbackAt     *time.Time     `json:"feedback_at"`
+	ReplacedByID   *uint          `json:"replaced_by_id"` // 发布者补发后指向新的 ClaimItem
+	ReplacedAt     *time.Time     `json:"replaced_at"`
+}
+
+// TierRule assigns a prize tier to claimers by their claim order. A claim whose
+// sequence number is at most FirstN receives a code from Tier; rules are checked
+// in order, so thresholds are cumulative ("first 10 get A, up to 30 get B").
+type TierRule struct {
+	Tier   string `json:"tier"`
+	FirstN int    `json:"first_n"`
+}
+
+// TierRules is a custom type for storing tier rules as JSON
+type TierRules []TierRule
+
+// Scan implements the sql.Scanner interface
+func (r *TierRules) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, r)
+}
+
+// Value implements the driver.Valuer interface
+func (r TierRules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// RiskReason is a fraud signal that contributed to a claim's risk score
+type RiskReason struct {
+	Signal string `json:"signal"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// RiskReasons is a custom type for the fraud signals stored on a claim
+type RiskReasons []RiskReason
+
+// Scan implements the sql.Scanner interface
+func (r *RiskReasons) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, r)
+}
+
+// Value implements the driver.Valuer interface
+func (r RiskReasons) Value() (driver.Value, error) {
+	return json.Marshal(r)
 }
 
 // StringSlice is a custom type for string slices in the database
@@ -58,6 +191,11 @@ type StringSlice []string
 
 // Scan implements the sql.Scanner interface
 func (ss *StringSlice) Scan(value interface{}) error {
+	if value == nil {
+		*ss = nil
+		return nil
+	}
+
 	bytes, ok := value.([]byte)
 	if !ok {
 		return errors.New("type assertion to []byte failed")
@@ -76,6 +214,11 @@ type JSON map[string]interface{}
 
 // Scan implements the sql.Scanner interface
 func (j *JSON) Scan(value interface{}) error {
+	if value == nil {
+		*j = nil
+		return nil
+	}
+
 	bytes, ok := value.([]byte)
 	if !ok {
 		return errors.New("type assertion to []byte failed")
@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+// UsedChallenge remembers a redeemed proof-of-work token until it expires, so it cannot be replayed
+type UsedChallenge struct {
+	Key       string    `json:"key" gorm:"primaryKey;type:varchar(64)"` // 令牌的 SHA-256
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
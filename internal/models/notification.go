@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationPreference holds a user's notification settings
+type NotificationPreference struct {
+	UserID           uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Locale           string    `json:"locale" gorm:"type:varchar(8);default:'zh'"` // zh/en
+	Email            string    `json:"email"`                                      // 为空时使用 OAuth 账户的邮箱
+	ClaimReceipt     bool      `json:"claim_receipt" gorm:"default:true"`          // 领取成功后发送兑换码副本
+	BenefitDepleted  bool      `json:"benefit_depleted" gorm:"default:true"`       // 福利领完时提醒创建者
+	BenefitExpiring  bool      `json:"benefit_expiring" gorm:"default:true"`       // 福利即将过期时提醒创建者
+	UnsubscribeToken string    `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// EmailNotification records an email sent for an event, so redelivered events are not mailed twice
+type EmailNotification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Key       string     `json:"key" gorm:"type:varchar(191);uniqueIndex"` // <事件 ID>:<通知类型>
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Kind      string     `json:"kind" gorm:"type:varchar(32)"`
+	To        string     `json:"to"`
+	Subject   string     `json:"subject"`
+	Status    string     `json:"status" gorm:"type:varchar(16);default:'pending'"` // pending/sent
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error" gorm:"type:text"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index:idx_notifications_user_read"`
+	Kind      string     `json:"kind" gorm:"type:varchar(32)"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body" gorm:"type:text"`
+	Link      string     `json:"link"`                                   // 前端路由，如 /dashboard/benefits/<uuid>
+	EventKey  string     `json:"-" gorm:"type:varchar(191);uniqueIndex"` // <事件 ID>:<用户 ID>，避免重复投递
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notifications_user_read"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent represents a domain event written in the same transaction as the change it describes
+type OutboxEvent struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UUID          string     `json:"uuid" gorm:"type:varchar(64);uniqueIndex"`
+	Type          string     `json:"type" gorm:"type:varchar(64)"`
+	Payload       string     `json:"payload" gorm:"type:mediumtext"`
+	Status        string     `json:"status" gorm:"type:varchar(16);default:'pending';index:idx_outbox_due"` // pending/dispatched
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_due"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DispatchedAt  *time.Time `json:"dispatched_at"`
+}
+
+// OutboxConsumption records that a subscriber has successfully handled an outbox event
+type OutboxConsumption struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	EventID    uint      `json:"event_id" gorm:"index:idx_outbox_event_subscriber,unique"`
+	Subscriber string    `json:"subscriber" gorm:"type:varchar(128);index:idx_outbox_event_subscriber,unique"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket is a token bucket shared by all replicas when rate limits are kept in the database
+type RateLimitBucket struct {
+	Key       string    `json:"key" gorm:"primaryKey;type:varchar(191)"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:false"`
+	FullAt    time.Time `json:"full_at" gorm:"index"` // 此后桶已装满，可以删除
+}
@@ -12,6 +12,7 @@ type User struct {
 	CreatedAt   time.Time `json:"created_at"`
 	LastLoginAt time.Time `json:"last_login_at"`
 	Status      string    `json:"status" gorm:"default:'active'"` // active/banned/deleted
+	Role        string    `json:"role" gorm:"default:'user'"`     // user/admin
 }
 
 // OAuthAccount represents a third-party OAuth account linked to a user
@@ -47,3 +48,13 @@ type OAuthProvider struct {
 	SortOrder    int       `json:"sort_order"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// CreatorBan prevents a user from claiming any benefit of a creator
+type CreatorBan struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatorID uint      `json:"creator_id" gorm:"index:idx_creator_user,unique"`
+	UserID    uint      `json:"user_id" gorm:"index:idx_creator_user,unique"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	Reason    string    `json:"reason" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
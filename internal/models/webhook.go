@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEndpoint represents a creator's outgoing webhook subscription
+type WebhookEndpoint struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	UserID    uint        `json:"user_id" gorm:"index"`
+	User      User        `json:"-" gorm:"foreignKey:UserID"`
+	URL       string      `json:"url" gorm:"type:text"`
+	Secret    string      `json:"-" gorm:"type:varchar(128)"` // 用于 HMAC-SHA256 签名
+	Events    StringSlice `json:"events" gorm:"type:json"`    // 为空表示订阅所有事件
+	Enabled   bool        `json:"enabled" gorm:"default:true"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// WebhookDelivery represents a single delivery of an event to a webhook endpoint
+type WebhookDelivery struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	UUID           string          `json:"uuid" gorm:"type:varchar(64);uniqueIndex"` // <事件 ID>:<endpoint ID>
+	EndpointID     uint            `json:"endpoint_id" gorm:"index"`
+	Endpoint       WebhookEndpoint `json:"-" gorm:"foreignKey:EndpointID"`
+	Event          string          `json:"event" gorm:"type:varchar(64)"`
+	Payload        string          `json:"payload" gorm:"type:mediumtext"`
+	Status         string          `json:"status" gorm:"type:varchar(16);default:'pending';index:idx_webhook_due"` // pending/succeeded/failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" gorm:"index:idx_webhook_due"`
+	LastError      string          `json:"last_error" gorm:"type:text"`
+	ResponseStatus int             `json:"response_status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/pkg/events"
+	"strconv"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Inbox-only notification kinds
+const (
+	KindBenefitClaimed = "benefit_claimed"
+	KindClaimApproved  = "claim_approved"
+	KindAccountStatus  = "account_status"
+)
+
+// inboxTemplates holds the title and body of inbox entries, keyed by "<kind>.<locale>"
+var inboxTemplates = map[string][2]*template.Template{
+	KindBenefitClaimed + ".zh":  inboxTemplate("「{{.Benefit.Title}}」有新的领取", "{{.Name}} 领取了 {{.Count}} 个兑换码，还剩 {{.Remaining}} 个。"),
+	KindBenefitClaimed + ".en":  inboxTemplate(`New claim on "{{.Benefit.Title}}"`, "{{.Name}} claimed {{.Count}} code(s), {{.Remaining}} left."),
+	KindClaimApproved + ".zh":   inboxTemplate("「{{.Benefit.Title}}」的领取已通过审核", "你领取的 {{.Count}} 个兑换码已经可以查看了。"),
+	KindClaimApproved + ".en":   inboxTemplate(`Your claim on "{{.Benefit.Title}}" was approved`, "The {{.Count}} code(s) you claimed are now available."),
+	KindBenefitDepleted + ".zh": inboxTemplate("「{{.Benefit.Title}}」已被领完", "全部 {{.Benefit.TotalCount}} 个兑换码均已发放。"),
+	KindBenefitDepleted + ".en": inboxTemplate(`"{{.Benefit.Title}}" has run out`, "All {{.Benefit.TotalCount}} codes have been claimed."),
+	KindBenefitExpiring + ".zh": inboxTemplate("「{{.Benefit.Title}}」即将过期", `将于 {{.Benefit.ExpiresAt.Format "2006-01-02 15:04"}} 过期，还有 {{.Remaining}} 个兑换码未被领取。`),
+	KindBenefitExpiring + ".en": inboxTemplate(`"{{.Benefit.Title}}" expires soon`, `It expires at {{.Benefit.ExpiresAt.Format "2006-01-02 15:04"}} with {{.Remaining}} codes unclaimed.`),
+	KindAccountStatus + ".zh":   inboxTemplate("账户状态已变更", "管理员将你的账户状态从 {{.PreviousStatus}} 改为 {{.Status}}。{{if .Reason}}原因：{{.Reason}}{{end}}"),
+	KindAccountStatus + ".en":   inboxTemplate("Your account status changed", "An administrator changed your account status from {{.PreviousStatus}} to {{.Status}}.{{if .Reason}} Reason: {{.Reason}}{{end}}"),
+}
+
+// inboxData is passed to the inbox templates
+type inboxData struct {
+	Name           string
+	Benefit        events.BenefitPayload
+	Count          int
+	Remaining      int
+	Status         string
+	PreviousStatus string
+	Reason         string
+}
+
+// inboxTemplate parses a title/body pair
+func inboxTemplate(title, body string) [2]*template.Template {
+	return [2]*template.Template{
+		template.Must(template.New("title").Parse(title)),
+		template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// InboxService manages users' in-app notifications
+type InboxService struct{}
+
+// NewInboxService creates a new inbox service
+func NewInboxService() *InboxService {
+	return &InboxService{}
+}
+
+// List returns the user's notifications, newest first, along with the total number of matches
+func (s *InboxService) List(userID uint, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error) {
+	query := db.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var notifications []models.Notification
+	err := query.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&notifications).Error
+	return notifications, total, err
+}
+
+// UnreadCount returns the number of unread notifications of the user
+func (s *InboxService) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks the given notifications of the user as read, or all of them when ids is empty.
+// It returns the number of notifications that changed.
+func (s *InboxService) MarkRead(userID uint, ids []uint) (int64, error) {
+	query := db.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// Push adds a notification to a user's inbox using tx. Notifications with an
+// EventKey that is already present are ignored, so redelivered events are harmless.
+func Push(tx *gorm.DB, notification *models.Notification) error {
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	if notification.EventKey == "" {
+		return tx.Create(notification).Error
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(notification).Error
+}
+
+// RegisterInbox subscribes the inbox to the events that produce in-app notifications
+func RegisterInbox(bus *events.Bus) error {
+	return bus.Subscribe("inbox", handleInboxEvent,
+		events.ClaimCreated, events.ClaimApproved, events.BenefitDepleted, events.BenefitExpiring, events.UserStatusChanged)
+}
+
+// handleInboxEvent turns an event into a notification for the affected user: the
+// creator of the benefit, the claimer of an approved claim or the user whose account
+// status changed
+func handleInboxEvent(ctx context.Context, e events.Event) error {
+	tx := db.DB.WithContext(ctx)
+
+	if e.Type == events.UserStatusChanged {
+		var payload events.UserEvent
+		if err := e.Decode(&payload); err != nil {
+			return err
+		}
+		return pushInbox(tx, e, payload.UserID, KindAccountStatus, "/dashboard", inboxData{
+			Status:         payload.Status,
+			PreviousStatus: payload.PreviousStatus,
+			Reason:         payload.Reason,
+		})
+	}
+
+	var payload events.BenefitEvent
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+
+	// 待审核的领取通过后，通知领取者兑换码已可查看
+	if e.Type == events.ClaimApproved {
+		if payload.Claim == nil {
+			return nil
+		}
+		return pushInbox(tx, e, payload.Claim.UserID, KindClaimApproved, "/dashboard/claims", inboxData{
+			Benefit: payload.Benefit,
+			Count:   payload.Claim.CodeCount,
+		})
+	}
+
+	data := inboxData{
+		Benefit:   payload.Benefit,
+		Remaining: payload.Benefit.TotalCount - payload.Benefit.ClaimedCount,
+	}
+	kind := KindBenefitDepleted
+	switch e.Type {
+	case events.ClaimCreated:
+		if payload.Claim == nil {
+			return nil
+		}
+		var claimer models.User
+		if err := tx.First(&claimer, payload.Claim.UserID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		kind = KindBenefitClaimed
+		data.Name = claimer.Username
+		data.Count = payload.Claim.CodeCount
+	case events.BenefitExpiring:
+		kind = KindBenefitExpiring
+	}
+
+	return pushInbox(tx, e, payload.Benefit.CreatorID, kind, "/dashboard/benefits/"+payload.Benefit.UUID, data)
+}
+
+// pushInbox renders a notification in the user's language and adds it to their inbox
+func pushInbox(tx *gorm.DB, e events.Event, userID uint, kind, link string, data inboxData) error {
+	prefs, err := loadPreferences(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	tmpl, ok := inboxTemplates[kind+"."+prefs.Locale]
+	if !ok {
+		tmpl = inboxTemplates[kind+"."+LocaleZH]
+	}
+
+	var title, body bytes.Buffer
+	if err := tmpl[0].Execute(&title, data); err != nil {
+		return err
+	}
+	if err := tmpl[1].Execute(&body, data); err != nil {
+		return err
+	}
+
+	return Push(tx, &models.Notification{
+		UserID:   userID,
+		Kind:     kind,
+		Title:    title.String(),
+		Body:     body.String(),
+		Link:     link,
+		EventKey: e.ID + ":" + strconv.FormatUint(uint64(userID), 10),
+	})
+}
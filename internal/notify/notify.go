@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/pkg/events"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notifier turns domain events into emails: claim receipts for claimers and
+// depleted/expiring alerts for creators, honouring each user's preferences.
+type Notifier struct {
+	mailer  Mailer
+	baseURL string // 前端地址，用于邮件中的页面链接
+	apiURL  string // 服务地址，用于退订链接
+}
+
+// NewNotifier creates a notifier sending through mailer. baseURL is the frontend
+// address used for page links in the emails, apiURL the public address of the API
+// serving the unsubscribe links.
+func NewNotifier(mailer Mailer, baseURL, apiURL string) *Notifier {
+	return &Notifier{
+		mailer:  mailer,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiURL:  strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// Register subscribes the notifier to the events it sends emails for
+func (n *Notifier) Register(bus *events.Bus) error {
+	return bus.Subscribe("email", n.handleEvent,
+		events.ClaimCreated, events.ClaimApproved, events.BenefitDepleted, events.BenefitExpiring)
+}
+
+// handleEvent dispatches an event to the matching email
+func (n *Notifier) handleEvent(ctx context.Context, e events.Event) error {
+	var payload events.BenefitEvent
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case events.ClaimCreated, events.ClaimApproved:
+		return n.sendClaimReceipt(ctx, e, &payload)
+	case events.BenefitDepleted:
+		return n.sendCreatorAlert(ctx, e, KindBenefitDepleted, &payload)
+	case events.BenefitExpiring:
+		return n.sendCreatorAlert(ctx, e, KindBenefitExpiring, &payload)
+	}
+
+	return nil
+}
+
+// sendClaimReceipt mails the claimed codes to the email of the account used to claim
+func (n *Notifier) sendClaimReceipt(ctx context.Context, e events.Event, payload *events.BenefitEvent) error {
+	if payload.Claim == nil {
+		return nil
+	}
+
+	var claim models.Claim
+	err := db.DB.WithContext(ctx).Preload("User").Preload("Items.RedemptionCode").First(&claim, payload.Claim.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 领取已被撤销或仍在等待发布者审核时不发送兑换码，审核通过后由 claim.approved 事件补发
+	if claim.Status == "revoked" || claim.ReviewStatus == "pending" {
+		return nil
+	}
+
+	prefs, err := loadPreferences(db.DB.WithContext(ctx), claim.UserID)
+	if err != nil {
+		return err
+	}
+	if !prefs.ClaimReceipt {
+		return nil
+	}
+
+	name := claim.User.Username
+	to := prefs.Email
+	var account models.OAuthAccount
+	err = db.DB.WithContext(ctx).
+		Where("user_id = ? AND provider = ? AND status = ?", claim.UserID, claim.OAuthProvider, "active").
+		First(&account).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if to == "" {
+		to = account.ProviderEmail
+	}
+	if account.ProviderUsername != "" {
+		name = account.ProviderUsername
+	}
+	if to == "" {
+		return nil
+	}
+
+	data := templateData{
+		Name:         name,
+		Benefit:      payload.Benefit,
+		DashboardURL: n.baseURL + "/dashboard/claims",
+	}
+	for _, item := range claim.Items {
+		if item.ReplacedAt != nil {
+			continue
+		}
+		code := item.RedemptionCode
+		data.Codes = append(data.Codes, codeData{
+			Code:         code.Code,
+			Tier:         code.Tier,
+			Platform:     code.Metadata.Platform,
+			Region:       code.Metadata.Region,
+			ExpiresAt:    code.Metadata.ExpiresAt,
+			Instructions: code.Metadata.Instructions,
+		})
+	}
+
+	return n.send(ctx, e, KindClaimReceipt, prefs, to, data)
+}
+
+// sendCreatorAlert mails a benefit alert to the benefit's creator
+func (n *Notifier) sendCreatorAlert(ctx context.Context, e events.Event, kind string, payload *events.BenefitEvent) error {
+	creatorID := payload.Benefit.CreatorID
+
+	prefs, err := loadPreferences(db.DB.WithContext(ctx), creatorID)
+	if err != nil {
+		return err
+	}
+	if !enabled(prefs, kind) {
+		return nil
+	}
+
+	var creator models.User
+	if err := db.DB.WithContext(ctx).First(&creator, creatorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	to := prefs.Email
+	if to == "" {
+		// 使用最近登录且带邮箱的 OAuth 账户
+		var account models.OAuthAccount
+		err := db.DB.WithContext(ctx).
+			Where("user_id = ? AND status = ? AND provider_email <> ''", creatorID, "active").
+			Order("last_used_at DESC").
+			First(&account).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		to = account.ProviderEmail
+	}
+	if to == "" {
+		return nil
+	}
+
+	data := templateData{
+		Name:         creator.Username,
+		Benefit:      payload.Benefit,
+		Remaining:    payload.Benefit.TotalCount - payload.Benefit.ClaimedCount,
+		DashboardURL: n.baseURL + "/dashboard/benefits/" + payload.Benefit.UUID,
+	}
+
+	return n.send(ctx, e, kind, prefs, to, data)
+}
+
+// send renders and delivers one email, at most once per event and kind.
+// A failed send is returned so the outbox retries the event later.
+func (n *Notifier) send(ctx context.Context, e events.Event, kind string, prefs *models.NotificationPreference, to string, data templateData) error {
+	unsubscribeURL := n.unsubscribeURL(prefs.UnsubscribeToken, kind)
+	data.UnsubscribeURL = unsubscribeURL
+
+	subject, text, html, err := render(kind, prefs.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	record := models.EmailNotification{
+		Key:       e.ID + ":" + kind,
+		UserID:    prefs.UserID,
+		Kind:      kind,
+		To:        to,
+		Subject:   subject,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+	tx := db.DB.WithContext(ctx)
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("`key` = ?", record.Key).First(&record).Error; err != nil {
+		return err
+	}
+	if record.Status == "sent" {
+		return nil
+	}
+
+	err = n.mailer.Send(ctx, Message{
+		To:      to,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+	if err != nil {
+		tx.Model(&record).Updates(map[string]interface{}{
+			"attempts":   record.Attempts + 1,
+			"last_error": err.Error(),
+		})
+		return err
+	}
+
+	now := time.Now()
+	return tx.Model(&record).Updates(map[string]interface{}{
+		"status":     "sent",
+		"attempts":   record.Attempts + 1,
+		"last_error": "",
+		"sent_at":    now,
+	}).Error
+}
+
+// unsubscribeURL builds the one-click unsubscribe link for kind
+func (n *Notifier) unsubscribeURL(token, kind string) string {
+	query := url.Values{}
+	query.Set("token", token)
+	query.Set("kind", kind)
+	return n.apiURL + "/api/notifications/unsubscribe?" + query.Encode()
+}
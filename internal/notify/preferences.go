@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"net/mail"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification kinds, used in preferences and unsubscribe links
+const (
+	KindClaimReceipt    = "claim_receipt"
+	KindBenefitDepleted = "benefit_depleted"
+	KindBenefitExpiring = "benefit_expiring"
+)
+
+var (
+	// ErrInvalidInput indicates invalid notification preferences
+	ErrInvalidInput = errors.New("invalid notification preferences")
+
+	// ErrInvalidToken indicates an unknown unsubscribe token
+	ErrInvalidToken = errors.New("invalid unsubscribe token")
+)
+
+// PreferenceInput represents a partial update of a user's notification preferences
+type PreferenceInput struct {
+	Locale          *string `json:"locale"`
+	Email           *string `json:"email"`
+	ClaimReceipt    *bool   `json:"claim_receipt"`
+	BenefitDepleted *bool   `json:"benefit_depleted"`
+	BenefitExpiring *bool   `json:"benefit_expiring"`
+}
+
+// PreferenceService manages users' notification preferences
+type PreferenceService struct{}
+
+// NewPreferenceService creates a new preference service
+func NewPreferenceService() *PreferenceService {
+	return &PreferenceService{}
+}
+
+// GetPreferences returns the user's preferences, creating the defaults on first access
+func (s *PreferenceService) GetPreferences(userID uint) (*models.NotificationPreference, error) {
+	return loadPreferences(db.DB, userID)
+}
+
+// UpdatePreferences applies the non-nil fields of input to the user's preferences
+func (s *PreferenceService) UpdatePreferences(userID uint, input PreferenceInput) (*models.NotificationPreference, error) {
+	prefs, err := loadPreferences(db.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if input.Locale != nil {
+		if *input.Locale != LocaleZH && *input.Locale != LocaleEN {
+			return nil, ErrInvalidInput
+		}
+		updates["locale"] = *input.Locale
+	}
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+		if email != "" {
+			if _, err := mail.ParseAddress(email); err != nil {
+				return nil, ErrInvalidInput
+			}
+		}
+		updates["email"] = email
+	}
+	if input.ClaimReceipt != nil {
+		updates["claim_receipt"] = *input.ClaimReceipt
+	}
+	if input.BenefitDepleted != nil {
+		updates["benefit_depleted"] = *input.BenefitDepleted
+	}
+	if input.BenefitExpiring != nil {
+		updates["benefit_expiring"] = *input.BenefitExpiring
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(prefs).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return loadPreferences(db.DB, userID)
+}
+
+// Unsubscribe turns off one kind of email (or all of them when kind is empty)
+// for the user owning token. It needs no login so it can be used from an email.
+func (s *PreferenceService) Unsubscribe(token, kind string) (*models.NotificationPreference, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var prefs models.NotificationPreference
+	if err := db.DB.Where("unsubscribe_token = ?", token).First(&prefs).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	switch kind {
+	case KindClaimReceipt, KindBenefitDepleted, KindBenefitExpiring:
+		updates[kind] = false
+	case "":
+		updates[KindClaimReceipt] = false
+		updates[KindBenefitDepleted] = false
+		updates[KindBenefitExpiring] = false
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	if err := db.DB.Model(&prefs).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return loadPreferences(db.DB, prefs.UserID)
+}
+
+// loadPreferences returns the user's preferences, inserting the defaults if none exist yet
+func loadPreferences(tx *gorm.DB, userID uint) (*models.NotificationPreference, error) {
+	var prefs models.NotificationPreference
+	err := tx.Where("user_id = ?", userID).First(&prefs).Error
+	if err == nil {
+		return &prefs, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发创建时以先写入的记录为准
+	defaults := models.NotificationPreference{
+		UserID:           userID,
+		Locale:           LocaleZH,
+		ClaimReceipt:     true,
+		BenefitDepleted:  true,
+		BenefitExpiring:  true,
+		UnsubscribeToken: token,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaults).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).First(&prefs).Error; err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// enabled reports whether the preferences allow emails of kind
+func enabled(prefs *models.NotificationPreference, kind string) bool {
+	switch kind {
+	case KindClaimReceipt:
+		return prefs.ClaimReceipt
+	case KindBenefitDepleted:
+		return prefs.BenefitDepleted
+	case KindBenefitExpiring:
+		return prefs.BenefitExpiring
+	}
+	return false
+}
+
+// generateToken creates a random unsubscribe token
+func generateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"giftredeem/pkg/events"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Supported locales
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templateData is passed to every email template
+type templateData struct {
+	Name           string
+	Benefit        events.BenefitPayload
+	Remaining      int
+	Codes          []codeData
+	DashboardURL   string
+	UnsubscribeURL string
+}
+
+// codeData describes a claimed code in a receipt
+type codeData struct {
+	Code         string
+	Tier         string
+	Platform     string
+	Region       string
+	ExpiresAt    *time.Time
+	Instructions string
+}
+
+// emailTemplate holds the parsed subject/text and HTML variants of one template file
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates maps "<kind>.<locale>" to its parsed template
+var templates = mustLoadTemplates()
+
+// mustLoadTemplates parses every embedded template file. Each file defines a
+// "subject", a "text" and an "html" template; the HTML one is parsed with
+// html/template so that user-provided titles and codes are escaped.
+func mustLoadTemplates() map[string]*emailTemplate {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]*emailTemplate, len(entries))
+	for _, entry := range entries {
+		path := "templates/" + entry.Name()
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+
+		result[name] = &emailTemplate{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS, path)),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, path)),
+		}
+	}
+
+	return result
+}
+
+// render executes the template of kind in locale, falling back to Chinese
+func render(kind, locale string, data templateData) (subject, text, html string, err error) {
+	tmpl, ok := templates[kind+"."+locale]
+	if !ok {
+		tmpl, ok = templates[kind+"."+LocaleZH]
+	}
+	if !ok {
+		return "", "", "", fmt.Errorf("no email template for %s", kind)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := tmpl.text.ExecuteTemplate(&buf, "text", data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err := tmpl.html.ExecuteTemplate(&buf, "html", data); err != nil {
+		return "", "", "", err
+	}
+	html = buf.String()
+
+	return subject, text, html, nil
+}
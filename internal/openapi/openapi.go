@@ -0,0 +1,462 @@
+// Package openapi builds an OpenAPI 3 document from a table of operations whose
+// parameters and bodies are Go types. Schemas are derived from the types by
+// reflection: JSON field names come from json tags, required request fields from
+// binding:"required", allowed values from enum tags and query parameters from query
+// tags. Every named struct becomes a component schema.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the version of the OpenAPI specification the documents conform to
+const Version = "3.0.3"
+
+// bearerScheme names the security scheme of JWT bearer tokens
+const bearerScheme = "bearerAuth"
+
+// Auth tells whether an operation needs a token
+type Auth int
+
+// Authentication requirements of an operation
+const (
+	AuthNone Auth = iota
+	AuthRequired
+	AuthOptional // 携带令牌时响应包含当前用户的信息
+)
+
+// Operation describes a single API endpoint
+type Operation struct {
+	ID           string // operationId，也是 Go 客户端中的方法名
+	Method       string // GET/POST/PUT/DELETE
+	Path         string // gin 路由格式，如 /api/claim/:uuid
+	Tag          string // 分组
+	Summary      string // 以动词开头的一句话，如 "Lists the benefits created by the current user"
+	Description  string // 可选的详细说明
+	Auth         Auth
+	Params       []Param     // 路径参数的类型和说明；未列出的路径参数为字符串
+	Query        interface{} // 查询参数，字段带 query 标签的结构体
+	Request      interface{} // 请求体
+	OptionalBody bool        // 请求体可以省略
+	Response     interface{} // 成功时响应中 data 的类型，nil 表示 data 为 null
+	ErrorData    interface{} // 部分错误响应中 data 的类型
+	Stream       bool        // 响应为 Server-Sent Events
+	Redirect     bool        // 浏览器访问时以 307 重定向响应
+}
+
+// Param describes a path parameter
+type Param struct {
+	Name        string
+	Type        interface{} // 参数类型的零值，如 uint(0)；为空时是字符串
+	Description string
+}
+
+// PathParams returns the parameters in the path of op, in order
+func (op Operation) PathParams() []Param {
+	var params []Param
+	for _, segment := range strings.Split(op.Path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		param := Param{Name: name, Type: ""}
+		for _, p := range op.Params {
+			if p.Name == name {
+				param = p
+				if param.Type == nil {
+					param.Type = ""
+				}
+			}
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// OpenAPIPath returns the path of op with {name} placeholders instead of :name
+func (op Operation) OpenAPIPath() string {
+	segments := strings.Split(op.Path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Info describes the API as a whole
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Tags       []tag                           `json:"tags,omitempty"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path/query
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as understood by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+// Build returns the document describing ops. It fails on types it cannot describe and
+// on different types sharing a name.
+func Build(info Info, ops []Operation) (*Document, error) {
+	b := &builder{
+		schemas: map[string]*Schema{},
+		types:   map[string]reflect.Type{},
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]operation{},
+		Components: components{
+			Schemas: b.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	seenTags := map[string]bool{}
+	for _, op := range ops {
+		o, err := b.operation(op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: %w", op.ID, err)
+		}
+		path := op.OpenAPIPath()
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]operation{}
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = o
+
+		if op.Tag != "" && !seenTags[op.Tag] {
+			seenTags[op.Tag] = true
+			doc.Tags = append(doc.Tags, tag{Name: op.Tag})
+		}
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return doc, nil
+}
+
+// MarshalIndent returns the document as indented JSON
+func (d *Document) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+type builder struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type // 组件名对应的 Go 类型，用于发现重名
+	err     error
+}
+
+func (b *builder) operation(op Operation) (operation, error) {
+	o := operation{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Responses:   map[string]response{},
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+
+	switch op.Auth {
+	case AuthRequired:
+		o.Security = []map[string][]string{{bearerScheme: {}}}
+	case AuthOptional:
+		o.Security = []map[string][]string{{}, {bearerScheme: {}}}
+	}
+
+	for _, p := range op.PathParams() {
+		o.Parameters = append(o.Parameters, parameter{
+			Name:        p.Name,
+			In:          "path",
+			Description: p.Description,
+			Required:    true,
+			Schema:      b.schema(reflect.TypeOf(p.Type)),
+		})
+	}
+	if op.Query != nil {
+		params, err := b.queryParams(reflect.TypeOf(op.Query))
+		if err != nil {
+			return o, err
+		}
+		o.Parameters = append(o.Parameters, params...)
+	}
+
+	if op.Request != nil {
+		o.RequestBody = &requestBody{
+			Required: !op.OptionalBody,
+			Content:  map[string]mediaType{"application/json": {Schema: b.schema(reflect.TypeOf(op.Request))}},
+		}
+	}
+
+	if op.Stream {
+		o.Responses["200"] = response{
+			Description: "Server-Sent Events; errors before the stream starts are returned as JSON",
+			Content:     map[string]mediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}},
+		}
+	} else {
+		var data *Schema
+		if op.Response != nil {
+			data = b.schema(reflect.TypeOf(op.Response))
+		}
+		description := "code is 0 on success; otherwise msg describes the error and data is usually null"
+		if op.ErrorData != nil {
+			errorData := b.schema(reflect.TypeOf(op.ErrorData))
+			data = &Schema{AnyOf: []*Schema{data, errorData}}
+			description = fmt.Sprintf("code is 0 on success; otherwise msg describes the error and data is null or, for some errors, %s", reflect.TypeOf(op.ErrorData).Name())
+		}
+		o.Responses["200"] = response{
+			Description: description,
+			Content:     map[string]mediaType{"application/json": {Schema: envelope(data)}},
+		}
+	}
+	if op.Redirect {
+		o.Responses[fmt.Sprint(http.StatusTemporaryRedirect)] = response{
+			Description: "Browsers are redirected to the frontend with the outcome in the query string",
+		}
+	}
+	return o, nil
+}
+
+// envelope wraps data in the {code, msg, data} body of every response
+func envelope(data *Schema) *Schema {
+	if data == nil {
+		data = &Schema{Nullable: true, Description: "always null"}
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer", Description: "0 on success, an error code otherwise"},
+			"msg":  {Type: "string"},
+			"data": data,
+		},
+		Required: []string{"code", "msg", "data"},
+	}
+}
+
+// queryParams describes the fields of a struct with query tags as query parameters
+func (b *builder) queryParams(t reflect.Type) ([]parameter, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query parameters must be a struct, not %s", t)
+	}
+	var params []parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		schema := b.schema(field.Type)
+		schema.Enum = enum(field)
+		// 查询参数不会是 null
+		schema.Nullable = false
+		params = append(params, parameter{
+			Name:     name,
+			In:       "query",
+			Required: field.Tag.Get("binding") == "required",
+			Schema:   schema,
+		})
+	}
+	return params, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema describes t, registering named structs as components
+func (b *builder) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := b.schema(t.Elem())
+		if s.Ref != "" {
+			// 3.0 中 $ref 的同级属性会被忽略，需要用 allOf 包一层
+			return &Schema{AllOf: []*Schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Slice, reflect.Array:
+		// nil 切片编码为 null
+		return &Schema{Type: "array", Items: b.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			b.fail(fmt.Errorf("map keys must be strings: %s", t))
+			return &Schema{}
+		}
+		var values interface{} = true
+		if t.Elem().Kind() != reflect.Interface {
+			values = b.schema(t.Elem())
+		}
+		return &Schema{Type: "object", AdditionalProperties: values, Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.component(t)
+	}
+
+	b.fail(fmt.Errorf("cannot describe type %s", t))
+	return &Schema{}
+}
+
+// component registers a named struct and returns a reference to it
+func (b *builder) component(t reflect.Type) *Schema {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if existing, ok := b.types[name]; ok {
+		if existing != t {
+			b.fail(fmt.Errorf("types %s and %s share the schema name %s", existing, t, name))
+		}
+		return ref
+	}
+	// 先占位再展开字段，以支持递归类型
+	b.types[name] = t
+	b.schemas[name] = &Schema{}
+	*b.schemas[name] = *b.object(t)
+	return ref
+}
+
+// object describes the JSON fields of a struct
+func (b *builder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := b.schema(field.Type)
+		if values := enum(field); values != nil {
+			property.Enum = values
+		}
+		s.Properties[name] = property
+		if field.Tag.Get("binding") == "required" {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+func (b *builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// enum returns the values allowed by the enum tag of field, if any
+func enum(field reflect.StructField) []string {
+	values, ok := field.Tag.Lookup("enum")
+	if !ok {
+		return nil
+	}
+	return strings.Split(values, ",")
+}
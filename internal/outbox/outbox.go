@@ -0,0 +1,196 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"giftredeem/pkg/events"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var logger = logging.For("outbox")
+
+const (
+	// maxBackoff caps the delay between two dispatch attempts of an event
+	maxBackoff = 10 * time.Minute
+
+	// leaseDuration keeps other dispatchers away from an event while it is being handled
+	leaseDuration = time.Minute
+)
+
+// Publish writes an event to the outbox using tx. The event is only dispatched if tx commits.
+func Publish(tx *gorm.DB, eventType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	event := models.OutboxEvent{
+		UUID:          uuid.New().String(),
+		Type:          eventType,
+		Payload:       string(body),
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	return tx.Create(&event).Error
+}
+
+// Dispatcher hands outbox events to the subscribers of a bus with at-least-once semantics.
+// Each subscriber's success is recorded separately, so a failing subscriber only causes
+// that subscriber to see the event again.
+type Dispatcher struct {
+	bus       *events.Bus
+	interval  time.Duration
+	batchSize int
+}
+
+// NewDispatcher creates a dispatcher for the given bus
+func NewDispatcher(bus *events.Bus) *Dispatcher {
+	return &Dispatcher{
+		bus:       bus,
+		interval:  time.Second,
+		batchSize: 50,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.processBatch(ctx); err != nil {
+			logger.Error("failed to dispatch events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processBatch leases a batch of due events and dispatches them
+func (d *Dispatcher) processBatch(ctx context.Context) error {
+	var pending []models.OutboxEvent
+	now := time.Now()
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", "pending", now).
+			Order("id").
+			Limit(d.batchSize).
+			Find(&pending).Error
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(pending))
+		for i, e := range pending {
+			ids[i] = e.ID
+		}
+
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(leaseDuration)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.dispatch(ctx, &pending[i])
+	}
+
+	return nil
+}
+
+// dispatch delivers one event to every subscriber that has not handled it yet
+func (d *Dispatcher) dispatch(ctx context.Context, record *models.OutboxEvent) {
+	var done []string
+	if err := db.DB.Model(&models.OutboxConsumption{}).Where("event_id = ?", record.ID).Pluck("subscriber", &done).Error; err != nil {
+		logger.Error("failed to load consumptions", "event_id", record.ID, "error", err)
+		return
+	}
+
+	handled := make(map[string]bool, len(done))
+	for _, name := range done {
+		handled[name] = true
+	}
+
+	event := events.Event{
+		ID:         record.UUID,
+		Type:       record.Type,
+		OccurredAt: record.CreatedAt,
+		Payload:    json.RawMessage(record.Payload),
+	}
+
+	var failures []string
+	for _, subscriber := range d.bus.Subscribers(record.Type) {
+		if handled[subscriber.Name] {
+			continue
+		}
+
+		if err := callHandler(ctx, subscriber, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", subscriber.Name, err))
+			continue
+		}
+
+		consumption := models.OutboxConsumption{EventID: record.ID, Subscriber: subscriber.Name, CreatedAt: time.Now()}
+		if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&consumption).Error; err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", subscriber.Name, err))
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": record.Attempts + 1}
+	if len(failures) == 0 {
+		updates["status"] = "dispatched"
+		updates["dispatched_at"] = now
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = strings.Join(failures, "; ")
+		updates["next_attempt_at"] = now.Add(backoff(record.Attempts + 1))
+	}
+
+	if err := db.DB.Model(record).Updates(updates).Error; err != nil {
+		logger.Error("failed to update event", "event_id", record.ID, "error", err)
+	}
+}
+
+// callHandler runs a subscriber, turning panics into errors so one bad handler cannot stop the dispatcher
+func callHandler(ctx context.Context, subscriber *events.Subscriber, event events.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint("panic: ", r))
+		}
+	}()
+	return subscriber.Handler(ctx, event)
+}
+
+// backoff returns the delay before the given attempt number
+func backoff(attempt int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
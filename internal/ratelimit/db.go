@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+var logger = logging.For("ratelimit")
+
+// DBStore keeps token buckets in the rate_limit_buckets table, so every replica
+// enforces the same limits. A token is taken with a single conditional UPDATE, so
+// requests sharing a hot key, such as a popular benefit, never wait on a row lock
+// held across round trips.
+type DBStore struct {
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDBStore creates a store backed by the database
+func NewDBStore() *DBStore {
+	return &DBStore{lastSweep: time.Now()}
+}
+
+// refilledSQL is the number of tokens in a bucket at the time given by the first
+// argument, for a refill rate and burst given by the second and third
+const refilledSQL = "LEAST(tokens + GREATEST(TIMESTAMPDIFF(MICROSECOND, updated_at, ?), 0) / 1000000 * ?, ?)"
+
+// takeSQL refills a bucket and removes a token if one is available. MySQL assigns
+// columns from left to right, so tokens is refilled from the previous updated_at
+// and full_at is computed from the new tokens.
+const takeSQL = "UPDATE rate_limit_buckets SET " +
+	"tokens = " + refilledSQL + " - 1, " +
+	"updated_at = ?, " +
+	"full_at = TIMESTAMPADD(MICROSECOND, ROUND((? - tokens) / ? * 1000000), ?) " +
+	"WHERE `key` = ? AND " + refilledSQL + " >= 1"
+
+// Take removes a token from the bucket identified by key. The remaining token
+// count is not read back, so Result.Remaining is always 0.
+func (s *DBStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+	s.maybeSweep(ctx, now)
+	conn := db.DB.WithContext(ctx)
+
+	// 插入新桶与其他请求并发冲突时，重新尝试一次更新
+	for attempt := 0; attempt < 2; attempt++ {
+		update := conn.Exec(takeSQL,
+			now, limit.Rate, limit.Burst,
+			now,
+			limit.Burst, limit.Rate, now,
+			key, now, limit.Rate, limit.Burst)
+		if update.Error != nil {
+			return Result{}, update.Error
+		}
+		if update.RowsAffected > 0 {
+			return Result{Allowed: true}, nil
+		}
+
+		// 桶不存在时创建一个已取出一个令牌的桶
+		tokens, result := take(float64(limit.Burst), limit)
+		bucket := models.RateLimitBucket{
+			Key:       key,
+			Tokens:    tokens,
+			UpdatedAt: now,
+			FullAt:    fullAt(tokens, now, limit),
+		}
+		insert := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket)
+		if insert.Error != nil {
+			return Result{}, insert.Error
+		}
+		if insert.RowsAffected > 0 {
+			return result, nil
+		}
+
+		// 桶已存在但令牌不足，读取桶的状态计算重试时间
+		var existing models.RateLimitBucket
+		if err := conn.Where("`key` = ?", key).First(&existing).Error; err != nil {
+			return Result{}, err
+		}
+		tokens = refill(existing.Tokens, existing.UpdatedAt, now, limit)
+		if tokens < 1 {
+			_, result = take(tokens, limit)
+			return result, nil
+		}
+	}
+
+	// 令牌在两次尝试之间被补充，仍与其他请求竞争失败时按限流处理
+	return Result{Allowed: false, RetryAfter: time.Second}, nil
+}
+
+// maybeSweep deletes buckets that have refilled completely, at most once per sweepInterval per process
+func (s *DBStore) maybeSweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	if err := db.DB.WithContext(ctx).Where("full_at <= ?", now).Delete(&models.RateLimitBucket{}).Error; err != nil {
+		logger.ErrorContext(ctx, "failed to delete idle buckets", "error", err)
+	}
+}
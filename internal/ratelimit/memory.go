@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps token buckets in process memory. Limits are enforced per
+// replica, which is enough for single-instance deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// memoryBucket is the state of a single token bucket
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time // 此后桶已装满，可以直接丢弃
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take removes a token from the bucket identified by key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	tokens := refill(b.tokens, b.updated, now, limit)
+	tokens, result := take(tokens, limit)
+
+	b.tokens = tokens
+	b.updated = now
+	b.fullAt = fullAt(tokens, now, limit)
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, since a missing bucket behaves the same
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
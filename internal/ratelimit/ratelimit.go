@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit indicates a limit that cannot be parsed
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit describes a token bucket: Rate tokens are added per second, up to Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerSecond allows n requests per second with the given burst
+func PerSecond(n float64, burst int) Limit {
+	return Limit{Rate: n, Burst: burst}
+}
+
+// PerMinute allows n requests per minute with the given burst
+func PerMinute(n float64, burst int) Limit {
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+// ParseLimit parses limits such as "10/m", "5/s" or "100/h:20", where the optional
+// number after the colon is the burst. Without it, the burst equals the count.
+func ParseLimit(value string) (Limit, error) {
+	spec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+
+	countSpec, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, value)
+	}
+
+	count, err := strconv.ParseFloat(countSpec, 64)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, value)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, value)
+	}
+
+	burst := int(math.Ceil(count))
+	if hasBurst {
+		burst, err = strconv.Atoi(burstSpec)
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, value)
+		}
+	}
+
+	return Limit{Rate: count / period.Seconds(), Burst: burst}, nil
+}
+
+// String formats the limit in the syntax accepted by ParseLimit
+func (l Limit) String() string {
+	count, unit := l.Rate, "s"
+	if count != math.Trunc(count) {
+		count, unit = l.Rate*60, "m"
+	}
+	if count != math.Trunc(count) {
+		count, unit = l.Rate*3600, "h"
+	}
+	return strconv.FormatFloat(count, 'f', -1, 64) + "/" + unit + ":" + strconv.Itoa(l.Burst)
+}
+
+// MarshalText implements encoding.TextMarshaler, so limits can be written to config files
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseLimit
+func (l *Limit) UnmarshalText(text []byte) error {
+	limit, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // 被拒绝时，距离下一个令牌可用的时间
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent use;
+// shared implementations let several replicas enforce a common limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Store kinds accepted by NewStore
+const (
+	StoreMemory = "memory"
+	StoreMySQL  = "mysql"
+)
+
+// NewStore returns the store of the given kind: "memory" (default) keeps buckets in
+// this process, "mysql" shares them through the database.
+func NewStore(kind string) (Store, error) {
+	switch kind {
+	case "", StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreMySQL:
+		return NewDBStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// refill returns the tokens in a bucket after the time elapsed since its last update
+func refill(tokens float64, last, now time.Time, limit Limit) float64 {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed > 0 {
+		tokens += elapsed * limit.Rate
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// take tries to remove one token from a bucket holding tokens, returning the new token count
+func take(tokens float64, limit Limit) (float64, Result) {
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+
+	wait := time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	return tokens, Result{Allowed: false, RetryAfter: wait}
+}
+
+// fullAt returns when a bucket holding tokens at now will be full again
+func fullAt(tokens float64, now time.Time, limit Limit) time.Time {
+	missing := float64(limit.Burst) - tokens
+	return now.Add(time.Duration(missing / limit.Rate * float64(time.Second)))
+}
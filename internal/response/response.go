@@ -18,6 +18,7 @@ const (
 	CodeUnauthorized = 401 // Unauthorized access
 	CodeForbidden    = 403 // Forbidden access
 	CodeNotFound     = 404 // Resource not found
+	CodeRateLimited  = 429 // Too many requests, retry after the Retry-After delay
 	CodeServerError  = 500 // Internal server error
 
 	// Authentication error codes (1000-1999)
@@ -36,6 +37,14 @@ const (
 	CodeBenefitNotActive      = 2004 // Benefit not active
 	CodeBenefitAlreadyClaimed = 2005 // User already claimed this benefit
 	CodeBenefitIneligible     = 2006 // User ineligible for this benefit
+	CodeBenefitPeriodLimit    = 2007 // User reached the claim limit for the current period
+	CodeClaimNotFound         = 2008 // Claim or claimed code not found
+	CodeReplacementNotAllowed = 2009 // Code cannot be replaced in its current state
+	CodeBenefitUserBanned     = 2010 // User is banned from the creator's benefits
+	CodeChallengeRequired     = 2011 // Benefit requires a proof-of-work or CAPTCHA challenge
+	CodeChallengeFailed       = 2012 // Challenge answer is wrong, expired or already used
+	CodeClaimBlocked          = 2013 // Claim attempt blocked by the benefit's fraud protection
+	CodeClaimNotUnderReview   = 2014 // Claim is neither flagged nor awaiting review
 )
 
 // Success creates a success response with data
@@ -55,3 +64,12 @@ func Error(code int, message string) StandardResponse {
 		Data: nil,
 	}
 }
+
+// ErrorWithData creates an error response carrying additional details
+func ErrorWithData(code int, message string, data interface{}) StandardResponse {
+	return StandardResponse{
+		Code: code,
+		Msg:  message,
+		Data: data,
+	}
+}
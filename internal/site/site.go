@@ -0,0 +1,212 @@
+// Package site holds the public addresses of the deployment: where the API and the
+// frontend are reachable and which paths OAuth providers redirect back to. Every
+// absolute URL the server hands out is built from here, so links and redirect URIs
+// stay consistent no matter which handler produces them.
+package site
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidURL indicates a configured URL that is not an absolute http(s) URL
+var ErrInvalidURL = errors.New("invalid site URL")
+
+// DefaultFrontendURL is used when neither FRONTEND_URL nor PUBLIC_URL is configured,
+// matching the frontend dev server
+const DefaultFrontendURL = "http://localhost:3000"
+
+// Default paths; {provider} is replaced with the OAuth provider name
+const (
+	DefaultOAuthCallbackPath    = "/api/auth/callback/{provider}"
+	DefaultFrontendCallbackPath = "/auth/callback/{provider}"
+	DefaultFrontendLoginPath    = "/login"
+)
+
+// Config describes the public addresses of the site
+type Config struct {
+	PublicURL            string   // 服务对外的规范地址，如 https://gift.example.com
+	FrontendURL          string   // 前端地址，默认与 PublicURL 相同
+	AllowedHosts         []string // 同样可以访问本站的其他域名（可带端口）
+	OAuthCallbackPath    string   // 在 OAuth 提供商处登记的回调路径
+	FrontendCallbackPath string   // 登录完成后前端接收令牌的路径
+	FrontendLoginPath    string   // 登录失败时返回的前端路径
+}
+
+// Validate checks the configured URLs and fills in default paths
+func (c *Config) Validate() error {
+	for _, value := range []string{c.PublicURL, c.FrontendURL} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidURL, value)
+		}
+	}
+
+	if c.OAuthCallbackPath == "" {
+		c.OAuthCallbackPath = DefaultOAuthCallbackPath
+	}
+	if c.FrontendCallbackPath == "" {
+		c.FrontendCallbackPath = DefaultFrontendCallbackPath
+	}
+	if c.FrontendLoginPath == "" {
+		c.FrontendLoginPath = DefaultFrontendLoginPath
+	}
+	for _, path := range []string{c.OAuthCallbackPath, c.FrontendCallbackPath, c.FrontendLoginPath} {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("%w: path %q must start with /", ErrInvalidURL, path)
+		}
+	}
+	return nil
+}
+
+// BaseURL returns scheme://host of the site for links returned to the client of req.
+// Requests to one of the allowed hosts keep their host, so each domain hands out
+// links to itself; any other host gets PUBLIC_URL. Without PUBLIC_URL the request's
+// own address is used, as resolved from trusted proxies. req may be nil.
+func (c Config) BaseURL(req *http.Request) string {
+	var host, scheme string
+	if req != nil {
+		host = req.Host
+		scheme = req.URL.Scheme
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	if c.PublicURL != "" {
+		public, _ := url.Parse(c.PublicURL)
+		if host == "" || strings.EqualFold(host, public.Host) || !c.isAllowedHost(host) {
+			return c.PublicURL
+		}
+		return public.Scheme + "://" + host
+	}
+
+	if len(c.AllowedHosts) > 0 && (host == "" || !c.isAllowedHost(host)) {
+		host = c.AllowedHosts[0]
+	}
+	if host == "" {
+		// 没有请求也没有配置时（如发送邮件），只能退回到本地开发地址
+		return DefaultFrontendURL
+	}
+	return scheme + "://" + host
+}
+
+// Frontend returns the frontend address for links that are not tied to a request,
+// such as those in emails
+func (c Config) Frontend() string {
+	return c.FrontendBase(nil)
+}
+
+// FrontendBase returns the frontend address for the client of req. req may be nil.
+func (c Config) FrontendBase(req *http.Request) string {
+	if c.FrontendURL != "" {
+		return c.FrontendURL
+	}
+	if c.PublicURL != "" || len(c.AllowedHosts) > 0 {
+		return c.BaseURL(req)
+	}
+	return DefaultFrontendURL
+}
+
+// OAuthCallbackURL returns the redirect URI registered with an OAuth provider. The
+// same URI must be sent when requesting authorization and when exchanging the code.
+func (c Config) OAuthCallbackURL(req *http.Request, provider string) string {
+	return c.BaseURL(req) + expand(c.OAuthCallbackPath, DefaultOAuthCallbackPath, provider)
+}
+
+// FrontendCallbackURL returns the frontend page that receives the token after login.
+// frontendURL overrides the frontend address when non-empty.
+func (c Config) FrontendCallbackURL(frontendURL, provider string) string {
+	if frontendURL == "" {
+		frontendURL = c.Frontend()
+	}
+	return frontendURL + expand(c.FrontendCallbackPath, DefaultFrontendCallbackPath, provider)
+}
+
+// FrontendLoginURL returns the frontend login page. frontendURL overrides the
+// frontend address when non-empty.
+func (c Config) FrontendLoginURL(frontendURL string) string {
+	if frontendURL == "" {
+		frontendURL = c.Frontend()
+	}
+	path := c.FrontendLoginPath
+	if path == "" {
+		path = DefaultFrontendLoginPath
+	}
+	return frontendURL + path
+}
+
+// IsAllowedURL reports whether value is an absolute http(s) URL on one of the site's
+// hosts, making it safe to redirect to
+func (c Config) IsAllowedURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+	return c.isAllowedHost(u.Host)
+}
+
+// isAllowedHost reports whether host serves this site: the host of PUBLIC_URL or
+// FRONTEND_URL, or one of ALLOWED_HOSTS
+func (c Config) isAllowedHost(host string) bool {
+	candidates := append([]string{}, c.AllowedHosts...)
+	urls := []string{c.PublicURL, c.FrontendURL}
+	if c.PublicURL == "" && c.FrontendURL == "" && len(c.AllowedHosts) == 0 {
+		urls = append(urls, DefaultFrontendURL)
+	}
+	for _, value := range urls {
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			candidates = append(candidates, u.Host)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand fills the provider name into a callback path
+func expand(path, def, provider string) string {
+	if path == "" {
+		path = def
+	}
+	return strings.ReplaceAll(path, "{provider}", url.PathEscape(provider))
+}
+
+var (
+	mu      sync.RWMutex
+	current = Config{
+		OAuthCallbackPath:    DefaultOAuthCallbackPath,
+		FrontendCallbackPath: DefaultFrontendCallbackPath,
+		FrontendLoginPath:    DefaultFrontendLoginPath,
+	}
+)
+
+// Configure validates cfg and makes it the site configuration used by handlers
+func Configure(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = cfg
+	return nil
+}
+
+// Current returns the site configuration
+func Current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
@@ -0,0 +1,117 @@
+// Package tracing provides OpenTelemetry tracing of HTTP requests, outbound calls to
+// OAuth providers and database queries. Tracing is disabled by default: until Setup
+// is called with Enabled set, the global tracer provider is a no-op and spans cost
+// next to nothing.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"giftredeem/internal/logging"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of this module
+const instrumentationName = "giftredeem"
+
+// DefaultEndpoint is the OTLP/HTTP endpoint of a collector on the local machine
+const DefaultEndpoint = "http://localhost:4318"
+
+// ErrInvalidConfig indicates unusable tracing settings
+var ErrInvalidConfig = errors.New("invalid tracing configuration")
+
+var logger = logging.For("tracing")
+
+// Config describes where spans are exported
+type Config struct {
+	Enabled     bool
+	Endpoint    string  // OTLP/HTTP 地址，如 http://localhost:4318；http 协议不使用 TLS
+	ServiceName string  // service.name 资源属性
+	SampleRatio float64 // 0~1，按 trace ID 采样；上游已决定采样的请求沿用上游的决定
+}
+
+// Validate checks the endpoint and the sample ratio
+func (c Config) Validate() error {
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("%w: sample ratio must be between 0 and 1", ErrInvalidConfig)
+	}
+	if !c.Enabled {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: endpoint %q must be an http or https URL", ErrInvalidConfig, c.Endpoint)
+	}
+	if c.ServiceName == "" {
+		return fmt.Errorf("%w: service name is required", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Setup installs the global tracer provider and propagator. The returned function
+// flushes pending spans and must be called on shutdown. With tracing disabled nothing
+// is installed and the function does nothing.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	// 导出失败（如采集器未启动）只记录日志，不影响请求
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("tracing error", "error", err)
+	}))
+
+	logger.Info("tracing enabled", "endpoint", cfg.Endpoint, "service", cfg.ServiceName, "sample_ratio", cfg.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used for the spans of this module
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts an internal span, e.g. around a transaction, so that its queries are
+// grouped under one operation
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
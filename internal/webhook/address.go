@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress indicates an endpoint that resolves to an internal address
+var ErrForbiddenAddress = errors.New("webhook endpoint resolves to a forbidden address")
+
+// Config configures the addresses webhooks may be delivered to
+type Config struct {
+	AllowLoopback bool // 允许投递到 127.0.0.1、::1 和 localhost，仅用于本地测试
+}
+
+// allowLoopback is set by Configure
+var allowLoopback atomic.Bool
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which some clouds use
+// for internal services such as metadata endpoints
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Configure applies the webhook settings. It must be called before endpoints are
+// created and before the worker is started.
+func Configure(cfg Config) {
+	allowLoopback.Store(cfg.AllowLoopback)
+}
+
+// allowedAddr reports whether deliveries may connect to addr. Private, loopback,
+// link-local, shared, multicast and unspecified addresses are refused so that creators
+// cannot use webhooks to reach services inside the deployment.
+func allowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return allowLoopback.Load()
+	}
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// allowedHost checks the host of an endpoint URL. Host names are only checked
+// again when connecting, since they may resolve differently by then.
+func allowedHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return allowedAddr(addr)
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return allowLoopback.Load()
+	}
+	return true
+}
+
+// dialControl refuses connections to forbidden addresses. It runs after DNS
+// resolution, for every address tried, so a host name that is rebound to an
+// internal address after the endpoint was saved is still refused.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !allowedAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// newHTTPClient returns the client deliveries are sent with. It ignores proxy
+// settings, which would bypass dialControl, and does not follow redirects.
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: dialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,344 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"giftredeem/internal/db"
+	"giftredeem/internal/models"
+	"giftredeem/pkg/events"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Events that can be delivered to webhook endpoints
+const (
+	EventBenefitCreated       = events.BenefitCreated
+	EventBenefitStatusChanged = events.BenefitStatusChanged
+	EventBenefitDepleted      = events.BenefitDepleted
+	EventBenefitExpiring      = events.BenefitExpiring
+	EventClaimCreated         = events.ClaimCreated
+	EventClaimApproved        = events.ClaimApproved
+	EventClaimCodeReplaced    = events.ClaimCodeReplaced
+	EventPing                 = "ping"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-GiftRedeem-Event"
+	HeaderDelivery  = "X-GiftRedeem-Delivery"
+	HeaderTimestamp = "X-GiftRedeem-Timestamp"
+	HeaderSignature = "X-GiftRedeem-Signature"
+)
+
+var (
+	// ErrInvalidInput indicates invalid webhook settings
+	ErrInvalidInput = errors.New("invalid webhook settings")
+
+	// ErrNotFound indicates the webhook endpoint does not exist or belongs to someone else
+	ErrNotFound = errors.New("webhook endpoint not found")
+)
+
+// supportedEvents lists the events an endpoint may subscribe to
+var supportedEvents = map[string]bool{
+	EventBenefitCreated:       true,
+	EventBenefitStatusChanged: true,
+	EventBenefitDepleted:      true,
+	EventBenefitExpiring:      true,
+	EventClaimCreated:         true,
+	EventClaimApproved:        true,
+	EventClaimCodeReplaced:    true,
+}
+
+// Payload is the JSON body delivered to webhook endpoints
+type Payload struct {
+	ID        string      `json:"id"` // 事件 ID，重试时保持不变
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService manages creators' webhook endpoints
+type WebhookService struct{}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService() *WebhookService {
+	return &WebhookService{}
+}
+
+// EndpointInput represents the input for creating or updating an endpoint
+type EndpointInput struct {
+	URL     string   `json:"url" binding:"required"`
+	Secret  string   `json:"secret"` // 为空时自动生成
+	Events  []string `json:"events"` // 为空表示订阅所有事件
+	Enabled *bool    `json:"enabled"`
+}
+
+// CreateEndpoint registers a new webhook endpoint for the user
+func (s *WebhookService) CreateEndpoint(userID uint, input EndpointInput) (*models.WebhookEndpoint, error) {
+	if err := validateInput(&input); err != nil {
+		return nil, err
+	}
+
+	if input.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		input.Secret = secret
+	}
+
+	endpoint := models.WebhookEndpoint{
+		UserID:  userID,
+		URL:     input.URL,
+		Secret:  input.Secret,
+		Events:  input.Events,
+		Enabled: input.Enabled == nil || *input.Enabled,
+	}
+
+	if err := db.DB.Create(&endpoint).Error; err != nil {
+		return nil, err
+	}
+
+	// gorm 不会写入 false 的默认值字段，需要单独更新
+	if !endpoint.Enabled {
+		if err := db.DB.Model(&endpoint).Update("enabled", false).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &endpoint, nil
+}
+
+// UpdateEndpoint changes the settings of one of the user's endpoints
+func (s *WebhookService) UpdateEndpoint(userID, endpointID uint, input EndpointInput) (*models.WebhookEndpoint, error) {
+	if err := validateInput(&input); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.GetEndpoint(userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"url":    input.URL,
+		"events": models.StringSlice(input.Events),
+	}
+	if input.Secret != "" {
+		updates["secret"] = input.Secret
+	}
+	if input.Enabled != nil {
+		updates["enabled"] = *input.Enabled
+	}
+
+	if err := db.DB.Model(endpoint).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetEndpoint(userID, endpointID)
+}
+
+// DeleteEndpoint removes one of the user's endpoints together with its delivery log
+func (s *WebhookService) DeleteEndpoint(userID, endpointID uint) error {
+	endpoint, err := s.GetEndpoint(userID, endpointID)
+	if err != nil {
+		return err
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(endpoint).Error
+	})
+}
+
+// GetEndpoint retrieves one of the user's endpoints
+func (s *WebhookService) GetEndpoint(userID, endpointID uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := db.DB.Where("id = ? AND user_id = ?", endpointID, userID).First(&endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+// GetUserEndpoints lists the user's endpoints
+func (s *WebhookService) GetUserEndpoints(userID uint) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := db.DB.Where("user_id = ?", userID).Order("id").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// GetDeliveries returns the most recent deliveries of one of the user's endpoints
+func (s *WebhookService) GetDeliveries(userID, endpointID uint, limit int) ([]models.WebhookDelivery, error) {
+	endpoint, err := s.GetEndpoint(userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var deliveries []models.WebhookDelivery
+	err = db.DB.Where("endpoint_id = ?", endpoint.ID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// SendTest queues a ping event for the endpoint, regardless of its event filter
+func (s *WebhookService) SendTest(userID, endpointID uint) (*models.WebhookDelivery, error) {
+	endpoint, err := s.GetEndpoint(userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := newDelivery(endpoint.ID, uuid.New().String(), EventPing, time.Now(), map[string]interface{}{
+		"endpoint_id": endpoint.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.DB.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Register subscribes the webhook fan-out to the benefit and claim events of bus
+func Register(bus *events.Bus) error {
+	return bus.Subscribe("webhook", handleEvent,
+		EventBenefitCreated, EventBenefitStatusChanged, EventBenefitDepleted, EventBenefitExpiring, EventClaimCreated, EventClaimApproved,
+		EventClaimCodeReplaced)
+}
+
+// handleEvent queues deliveries of an outbox event to the benefit creator's endpoints
+func handleEvent(ctx context.Context, e events.Event) error {
+	var payload events.BenefitEvent
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+
+	return Enqueue(db.DB.WithContext(ctx), payload.Benefit.CreatorID, e, e.Payload)
+}
+
+// Enqueue stores a delivery of the event for every enabled endpoint of the user subscribed to it.
+// Deliveries are keyed by event and endpoint, so enqueueing the same event twice is a no-op.
+func Enqueue(tx *gorm.DB, userID uint, e events.Event, data interface{}) error {
+	var endpoints []models.WebhookEndpoint
+	if err := tx.Where("user_id = ? AND enabled = ?", userID, true).Find(&endpoints).Error; err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !subscribed(&endpoint, e.Type) {
+			continue
+		}
+
+		delivery, err := newDelivery(endpoint.ID, e.ID, e.Type, e.OccurredAt, data)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sign computes the signature sent in the X-GiftRedeem-Signature header. Receivers should
+// recompute HMAC-SHA256 over "<timestamp>.<body>" with the endpoint secret and compare.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDelivery builds a pending delivery with its serialized payload
+func newDelivery(endpointID uint, eventID, event string, occurredAt time.Time, data interface{}) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	payload := Payload{
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: occurredAt,
+		Data:      data,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookDelivery{
+		UUID:          fmt.Sprintf("%s:%d", eventID, endpointID),
+		EndpointID:    endpointID,
+		Event:         event,
+		Payload:       string(body),
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// subscribed reports whether the endpoint wants to receive event
+func subscribed(endpoint *models.WebhookEndpoint, event string) bool {
+	if len(endpoint.Events) == 0 {
+		return true
+	}
+	for _, e := range endpoint.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// validateInput checks the endpoint URL and event filter. URLs pointing at internal
+// addresses are rejected early; deliveries check the resolved address again.
+func validateInput(input *EndpointInput) error {
+	input.URL = strings.TrimSpace(input.URL)
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidInput
+	}
+	if !allowedHost(u.Hostname()) {
+		return ErrInvalidInput
+	}
+
+	for _, event := range input.Events {
+		if !supportedEvents[event] {
+			return ErrInvalidInput
+		}
+	}
+
+	return nil
+}
+
+// generateSecret creates a random signing secret
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"giftredeem/internal/db"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/models"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var logger = logging.For("webhook")
+
+const (
+	// maxAttempts is the number of deliveries tried before a delivery is marked failed
+	maxAttempts = 8
+
+	// baseBackoff is the delay before the first retry; it doubles with every attempt
+	baseBackoff = 30 * time.Second
+
+	// maxBackoff caps the delay between two attempts
+	maxBackoff = 6 * time.Hour
+
+	// leaseDuration keeps other workers away from a delivery while it is being sent
+	leaseDuration = 2 * time.Minute
+)
+
+// Worker delivers queued webhook events, retrying failures with exponential backoff.
+// Deliveries live in the database, so several replicas may run a worker concurrently.
+type Worker struct {
+	client    *http.Client
+	interval  time.Duration
+	batchSize int
+}
+
+// NewWorker creates a new delivery worker
+func NewWorker() *Worker {
+	return &Worker{
+		client:    newHTTPClient(),
+		interval:  5 * time.Second,
+		batchSize: 20,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.processBatch(ctx); err != nil {
+			logger.Error("failed to process deliveries", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processBatch leases a batch of due deliveries and sends them
+func (w *Worker) processBatch(ctx context.Context) error {
+	var deliveries []models.WebhookDelivery
+	now := time.Now()
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", "pending", now).
+			Order("next_attempt_at").
+			Limit(w.batchSize).
+			Preload("Endpoint").
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+
+		// 租约：在发送期间推迟下一次尝试时间，避免其他实例重复投递
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(leaseDuration)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.deliver(ctx, &deliveries[i])
+	}
+
+	return nil
+}
+
+// deliver sends a single delivery and records the outcome
+func (w *Worker) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	statusCode, err := w.send(ctx, delivery)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"response_status": statusCode,
+		"last_error":      "",
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = "succeeded"
+		updates["delivered_at"] = now
+	case !delivery.Endpoint.Enabled || delivery.Attempts+1 >= maxAttempts:
+		updates["status"] = "failed"
+		updates["last_error"] = err.Error()
+	default:
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = now.Add(backoff(delivery.Attempts + 1))
+	}
+
+	if err := db.DB.Model(delivery).Updates(updates).Error; err != nil {
+		logger.Error("failed to update delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send posts the payload to the endpoint, returning the HTTP status received
+func (w *Worker) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	if !delivery.Endpoint.Enabled {
+		return 0, fmt.Errorf("endpoint disabled")
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GiftRedeem-Webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.UUID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Endpoint.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// 响应内容不写入投递日志，以免把内部服务的响应暴露给创建者；读取少量内容以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given attempt number
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
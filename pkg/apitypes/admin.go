@@ -0,0 +1,34 @@
+package apitypes
+
+import "time"
+
+// AdminAuditQuery filters and pages the global audit log
+type AdminAuditQuery struct {
+	Action     string     `query:"action"`
+	TargetType string     `query:"target_type"`
+	TargetID   string     `query:"target_id"`
+	ActorID    *uint      `query:"actor_id"`
+	BenefitID  *uint      `query:"benefit_id"`
+	Since      *time.Time `query:"since"`
+	Until      *time.Time `query:"until"`
+	Page       int        `query:"page"`      // 默认 1
+	PageSize   int        `query:"page_size"` // 默认 50
+}
+
+// UpdateUserStatusRequest bans, restores or deletes a user account
+type UpdateUserStatusRequest struct {
+	Status string `json:"status" binding:"required" enum:"active,banned,deleted"`
+	Reason string `json:"reason"` // 记录在审计日志中
+}
+
+// UserStatus is the status of a user account
+type UserStatus struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Status   string `json:"status" enum:"active,banned,deleted"`
+}
+
+// UserStatusResult wraps the updated user account
+type UserStatusResult struct {
+	User UserStatus `json:"user"`
+}
@@ -0,0 +1,76 @@
+// Package apitypes defines the request and response bodies of the GiftRedeem HTTP API.
+// The server encodes its responses from these types, the OpenAPI document served at
+// /api/openapi.json is generated from them, and the Go client in pkg/client decodes
+// into them, so the three cannot drift apart.
+//
+// Every response is wrapped in an envelope {"code": 0, "msg": "success", "data": ...};
+// the types below describe the data member. A non-zero code reports an error.
+package apitypes
+
+import "time"
+
+// Provider is an enabled OAuth provider users can log in with
+type Provider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// ProviderList lists the enabled OAuth providers
+type ProviderList struct {
+	Providers []Provider `json:"providers"`
+}
+
+// AuthURL is where the browser is sent to log in with a provider
+type AuthURL struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// VerifyCodeRequest exchanges an OAuth authorization code for a token
+type VerifyCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CallbackQuery holds the parameters of the OAuth callback
+type CallbackQuery struct {
+	Code         string `query:"code"`
+	State        string `query:"state"`
+	RedirectURI  string `query:"redirect_uri"`              // 登录后返回的前端地址，只接受本站域名
+	ResponseType string `query:"response_type" enum:"json"` // json 时返回 JSON 而不是重定向
+}
+
+// UserSummary identifies a logged-in user
+type UserSummary struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// LoginResult is the outcome of a successful login
+type LoginResult struct {
+	Token string      `json:"token"` // JWT，以 Authorization: Bearer <token> 携带
+	User  UserSummary `json:"user"`
+}
+
+// Account is an OAuth account bound to a user
+type Account struct {
+	Provider         string    `json:"provider"`
+	ProviderUsername string    `json:"provider_username"`
+	ProviderEmail    string    `json:"provider_email"`
+	ProviderAvatar   string    `json:"provider_avatar"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastUsedAt       time.Time `json:"last_used_at"`
+}
+
+// Profile describes the current user and their OAuth accounts
+type Profile struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url"`
+	CreatedAt time.Time `json:"created_at"`
+	Accounts  []Account `json:"accounts"`
+}
+
+// ProfileResult wraps the profile of the current user
+type ProfileResult struct {
+	User Profile `json:"user"`
+}
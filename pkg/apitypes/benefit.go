@@ -0,0 +1,382 @@
+package apitypes
+
+import "time"
+
+// CodeMetadata describes where and how a redemption code can be used
+type CodeMetadata struct {
+	Platform     string     `json:"platform"` // steam/psn/app_store/...
+	Region       string     `json:"region"`
+	FaceValue    string     `json:"face_value"`
+	ExpiresAt    *time.Time `json:"expires_at"` // 兑换码本身的过期时间，可能为空
+	Instructions string     `json:"instructions"`
+}
+
+// TierRule assigns a prize tier to claimers by their claim order; rules are checked
+// in order, so thresholds are cumulative
+type TierRule struct {
+	Tier   string `json:"tier"`
+	FirstN int    `json:"first_n"`
+}
+
+// CodeEntry is a redemption code imported with its own metadata
+type CodeEntry struct {
+	Code     string       `json:"code" binding:"required"`
+	Tier     string       `json:"tier"` // 必须是 Tiers 中已声明的档位，空字符串表示默认档位
+	Metadata CodeMetadata `json:"metadata"`
+}
+
+// TierInput declares a prize tier and the codes imported into it
+type TierInput struct {
+	Name     string        `json:"name" binding:"required"`
+	Weight   int           `json:"weight"`
+	Value    float64       `json:"value"`
+	Codes    []string      `json:"codes"`
+	Metadata *CodeMetadata `json:"metadata"` // 覆盖 CreateBenefitRequest.CodeMetadata 中的默认值
+}
+
+// CreateBenefitRequest creates a benefit together with its redemption codes
+type CreateBenefitRequest struct {
+	Title               string                 `json:"title" binding:"required"`
+	Description         string                 `json:"description"`
+	Codes               []string               `json:"codes"`   // 默认档位的兑换码
+	Entries             []CodeEntry            `json:"entries"` // 带有独立元数据的兑换码
+	Tiers               []TierInput            `json:"tiers"`   // 分档位的兑换码，可与 Codes 同时使用
+	ExpiresAt           *time.Time             `json:"expires_at"`
+	AllowedProviders    []string               `json:"allowed_providers"`
+	MinAccountAge       int                    `json:"min_account_age"`
+	ClaimConditions     map[string]interface{} `json:"claim_conditions"`
+	PerUserQuota        int                    `json:"per_user_quota"`  // 默认 1
+	CodesPerClaim       int                    `json:"codes_per_claim"` // 默认 1
+	PeriodLimit         int                    `json:"period_limit"`
+	LimitPeriod         string                 `json:"limit_period" enum:",day,week,month"`
+	AllocationMode      string                 `json:"allocation_mode" enum:",sequential,weighted"` // 默认 sequential
+	TierRules           []TierRule             `json:"tier_rules"`
+	CodeFormat          string                 `json:"code_format"`   // 空字符串表示不校验格式
+	CodePattern         string                 `json:"code_pattern"`  // CodeFormat 为 regex 时使用
+	CodeMetadata        CodeMetadata           `json:"code_metadata"` // 所有兑换码的默认元数据
+	Challenge           string                 `json:"challenge" enum:",pow,captcha"`
+	ChallengeDifficulty int                    `json:"challenge_difficulty"`
+	FraudThreshold      int                    `json:"fraud_threshold"`                        // 0 表示不启用风控
+	FraudAction         string                 `json:"fraud_action" enum:",block,flag,review"` // 默认 flag
+	Private             bool                   `json:"private"`                                // 分享链接时不展示标题等详情
+}
+
+// LineError describes a single malformed code found during import
+type LineError struct {
+	Source string `json:"source"` // codes / entries / tiers[i].codes
+	Line   int    `json:"line"`   // 从 1 开始的行号
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// ImportErrorDetail is the data of the error returned when some imported codes do not
+// match the benefit's code format
+type ImportErrorDetail struct {
+	InvalidCodes []LineError `json:"invalid_codes"`
+}
+
+// Benefit is a benefit as seen by its creator
+type Benefit struct {
+	ID                  uint       `json:"id"`
+	UUID                string     `json:"uuid"`
+	Title               string     `json:"title"`
+	Description         string     `json:"description"`
+	TotalCount          int        `json:"total_count"`
+	ClaimedCount        int        `json:"claimed_count"`
+	CreatedAt           time.Time  `json:"created_at"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+	Status              string     `json:"status" enum:"active,paused,expired,deleted"`
+	ClaimURL            string     `json:"claim_url"`
+	AllowedProviders    []string   `json:"allowed_providers"`
+	MinAccountAge       int        `json:"min_account_age"`
+	PerUserQuota        int        `json:"per_user_quota"`
+	CodesPerClaim       int        `json:"codes_per_claim"`
+	PeriodLimit         int        `json:"period_limit"`
+	LimitPeriod         string     `json:"limit_period"`
+	AllocationMode      string     `json:"allocation_mode"`
+	TierRules           []TierRule `json:"tier_rules"`
+	CodeFormat          string     `json:"code_format"`
+	Challenge           string     `json:"challenge"`
+	ChallengeDifficulty int        `json:"challenge_difficulty"`
+	FraudThreshold      int        `json:"fraud_threshold"`
+	FraudAction         string     `json:"fraud_action"`
+	Private             bool       `json:"private"`
+}
+
+// CreateBenefitResult is the benefit just created and the link to share
+type CreateBenefitResult struct {
+	Benefit  Benefit `json:"benefit"`
+	ClaimURL string  `json:"claim_url"`
+}
+
+// BenefitList lists the benefits created by the current user
+type BenefitList struct {
+	Benefits []Benefit `json:"benefits"`
+}
+
+// UpdateBenefitStatusRequest pauses, resumes or deletes a benefit
+type UpdateBenefitStatusRequest struct {
+	Status string `json:"status" binding:"required" enum:"active,paused,expired,deleted"`
+}
+
+// Creator identifies the publisher of a benefit
+type Creator struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// PublicBenefit is a benefit as shown on its claim page
+type PublicBenefit struct {
+	UUID             string    `json:"uuid"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	TotalCount       int       `json:"total_count"`
+	ClaimedCount     int       `json:"claimed_count"`
+	CreatedAt        time.Time `json:"created_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	Creator          Creator   `json:"creator"`
+	AllowedProviders []string  `json:"allowed_providers"`
+	MinAccountAge    int       `json:"min_account_age"`
+	PerUserQuota     int       `json:"per_user_quota"`
+	CodesPerClaim    int       `json:"codes_per_claim"`
+	PeriodLimit      int       `json:"period_limit"`
+	LimitPeriod      string    `json:"limit_period"`
+	Challenge        string    `json:"challenge"`
+}
+
+// BenefitInfo is a benefit and whether the current user can still claim it
+type BenefitInfo struct {
+	Benefit         PublicBenefit `json:"benefit"`
+	ClaimStatus     string        `json:"claim_status" enum:"available,claimed,period_limit_reached,paused,expired"`
+	RemainingQuota  int           `json:"remaining_quota"`            // 未登录时为福利的每人配额
+	PeriodRemaining *int          `json:"period_remaining,omitempty"` // 当前周期内还能领取的次数，仅在登录且福利有周期限制时返回
+	PeriodResetsAt  *time.Time    `json:"period_resets_at,omitempty"` // 周期限制重置的时间
+}
+
+// PoW is a hashcash-style challenge: find a solution such that
+// SHA-256(Token + solution) starts with at least Difficulty zero bits
+type PoW struct {
+	Algorithm  string    `json:"algorithm"` // 固定为 sha256
+	Token      string    `json:"token"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Challenge tells the client what it has to solve before claiming
+type Challenge struct {
+	Type    string `json:"type" enum:",pow,captcha"`
+	PoW     *PoW   `json:"pow,omitempty"`
+	SiteKey string `json:"site_key,omitempty"`
+}
+
+// ClaimRequest carries the answer to the benefit's challenge, if any
+type ClaimRequest struct {
+	ChallengeToken    string `json:"challenge_token"`
+	ChallengeSolution string `json:"challenge_solution"`
+	CaptchaResponse   string `json:"captcha_response"`
+}
+
+// BenefitRef identifies the benefit a claim belongs to
+type BenefitRef struct {
+	ID          uint   `json:"id"`
+	UUID        string `json:"uuid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ClaimItem is a single code handed out by a claim
+type ClaimItem struct {
+	ID           uint         `json:"id"`
+	Code         string       `json:"code"`
+	Tier         string       `json:"tier"`
+	TierValue    float64      `json:"tier_value"`
+	Metadata     CodeMetadata `json:"metadata"`
+	Feedback     string       `json:"feedback"` // 空/redeemed/invalid/already_used
+	FeedbackNote string       `json:"feedback_note"`
+	FeedbackAt   *time.Time   `json:"feedback_at"`
+	ReplacedByID *uint        `json:"replaced_by_id"` // 发布者补发后指向新的兑换码
+	ReplacedAt   *time.Time   `json:"replaced_at"`
+}
+
+// Claim is a claim as seen by the claimer. The codes of a claim held for the
+// creator's review are left out until it is approved.
+type Claim struct {
+	ID            uint        `json:"id"`
+	ClaimedAt     time.Time   `json:"claimed_at"`
+	OAuthProvider string      `json:"oauth_provider"`
+	ReviewStatus  string      `json:"review_status"` // 空/flagged/pending/approved/rejected
+	Benefit       BenefitRef  `json:"benefit"`
+	Code          string      `json:"code"`  // 第一个兑换码，兼容旧客户端
+	Codes         []string    `json:"codes"` // 未被替换的兑换码
+	Items         []ClaimItem `json:"items"`
+}
+
+// ClaimResult wraps a claim just made, with the counts of the benefit including it
+type ClaimResult struct {
+	Claim        Claim `json:"claim"`
+	ClaimedCount int   `json:"claimed_count"`
+	TotalCount   int   `json:"total_count"`
+}
+
+// ClaimList lists the claims of the current user
+type ClaimList struct {
+	Claims []Claim `json:"claims"`
+}
+
+// UserRef identifies the user who made a claim
+type UserRef struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// RiskReason is a fraud signal that contributed to a claim's risk score
+type RiskReason struct {
+	Signal string `json:"signal"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// BenefitClaim is a claim as seen by the creator of the benefit
+type BenefitClaim struct {
+	ID            uint         `json:"id"`
+	ClaimedAt     time.Time    `json:"claimed_at"`
+	OAuthProvider string       `json:"oauth_provider"`
+	Status        string       `json:"status" enum:"active,revoked"`
+	RevokeReason  string       `json:"revoke_reason"`
+	IPAddress     string       `json:"ip_address"`
+	RiskScore     int          `json:"risk_score"`
+	RiskReasons   []RiskReason `json:"risk_reasons"`
+	ReviewStatus  string       `json:"review_status"`
+	ReviewedAt    *time.Time   `json:"reviewed_at"`
+	User          UserRef      `json:"user"`
+	Code          string       `json:"code"`
+	Codes         []string     `json:"codes"`
+	Items         []ClaimItem  `json:"items"`
+}
+
+// TierStat summarizes the codes of a single tier within a benefit
+type TierStat struct {
+	Tier    string  `json:"tier"`
+	Weight  int     `json:"weight"`
+	Value   float64 `json:"value"`
+	Total   int     `json:"total"`
+	Claimed int     `json:"claimed"`
+}
+
+// FeedbackSummary counts the claimed codes of a benefit by the claimers' feedback
+type FeedbackSummary struct {
+	Redeemed    int `json:"redeemed"`
+	Invalid     int `json:"invalid"`
+	AlreadyUsed int `json:"already_used"`
+	NoFeedback  int `json:"no_feedback"`
+	Replaced    int `json:"replaced"`
+}
+
+// BenefitClaims lists the claims on a benefit with statistics over its codes
+type BenefitClaims struct {
+	Claims           []BenefitClaim   `json:"claims"`
+	TierDistribution []TierStat       `json:"tier_distribution"`
+	FeedbackSummary  *FeedbackSummary `json:"feedback_summary"`
+}
+
+// FeedbackRequest reports whether a claimed code worked
+type FeedbackRequest struct {
+	ItemID   uint   `json:"item_id"` // 只有一个兑换码的领取可以为 0
+	Feedback string `json:"feedback" binding:"required" enum:"redeemed,invalid,already_used"`
+	Note     string `json:"note"`
+}
+
+// FeedbackItem is the feedback recorded on a claimed code
+type FeedbackItem struct {
+	ID           uint       `json:"id"`
+	Feedback     string     `json:"feedback"`
+	FeedbackNote string     `json:"feedback_note"`
+	FeedbackAt   *time.Time `json:"feedback_at"`
+}
+
+// FeedbackResult wraps the feedback just recorded
+type FeedbackResult struct {
+	Item FeedbackItem `json:"item"`
+}
+
+// ReplaceCodeRequest selects the code of a claim to replace
+type ReplaceCodeRequest struct {
+	ItemID uint `json:"item_id"` // 只有一个兑换码的领取可以为 0
+}
+
+// ClaimItemResult wraps the code issued as a replacement
+type ClaimItemResult struct {
+	Item ClaimItem `json:"item"`
+}
+
+// RevokeClaimRequest voids a claim
+type RevokeClaimRequest struct {
+	Reason     string `json:"reason" binding:"required"`
+	CodeAction string `json:"code_action" enum:",return,burn"` // 默认 burn
+	BanUser    bool   `json:"ban_user"`                        // 禁止该用户领取发布者今后的福利
+}
+
+// RevokedClaim is a claim just revoked
+type RevokedClaim struct {
+	ID           uint       `json:"id"`
+	Status       string     `json:"status"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `json:"revoke_reason"`
+}
+
+// RevokeClaimResult wraps the claim just revoked
+type RevokeClaimResult struct {
+	Claim RevokedClaim `json:"claim"`
+}
+
+// ReviewClaimRequest approves or rejects a flagged or held claim
+type ReviewClaimRequest struct {
+	Decision string `json:"decision" binding:"required" enum:"approve,reject"`
+	Reason   string `json:"reason"` // 拒绝时必填
+}
+
+// ReviewedClaim is a claim just reviewed
+type ReviewedClaim struct {
+	ID           uint       `json:"id"`
+	Status       string     `json:"status"`
+	ReviewStatus string     `json:"review_status"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+	RevokeReason string     `json:"revoke_reason"`
+}
+
+// ReviewClaimResult wraps the claim just reviewed
+type ReviewClaimResult struct {
+	Claim ReviewedClaim `json:"claim"`
+}
+
+// AuditQuery filters and pages audit events
+type AuditQuery struct {
+	Action     string     `query:"action"`
+	TargetType string     `query:"target_type"`
+	TargetID   string     `query:"target_id"`
+	Since      *time.Time `query:"since"`
+	Until      *time.Time `query:"until"`
+	Page       int        `query:"page"`      // 默认 1
+	PageSize   int        `query:"page_size"` // 默认 50
+}
+
+// AuditEvent records a change made to a benefit, a claim or a user
+type AuditEvent struct {
+	ID         uint                   `json:"id"`
+	ActorID    *uint                  `json:"actor_id"` // 为空表示系统或匿名操作
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	BenefitID  *uint                  `json:"benefit_id"`
+	Before     map[string]interface{} `json:"before"`
+	After      map[string]interface{} `json:"after"`
+	IPAddress  string                 `json:"ip_address"`
+	UserAgent  string                 `json:"user_agent"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditPage is a page of audit events
+type AuditPage struct {
+	Events []AuditEvent `json:"events"`
+	Total  int64        `json:"total"`
+}
@@ -0,0 +1,71 @@
+package apitypes
+
+import "time"
+
+// Notification is an entry in the current user's in-app inbox
+type Notification struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link"`    // 前端路由，如 /dashboard/benefits/<uuid>
+	ReadAt    *time.Time `json:"read_at"` // 为空表示未读
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationQuery filters and pages the inbox
+type NotificationQuery struct {
+	Unread   bool `query:"unread"`    // 只返回未读的通知
+	Page     int  `query:"page"`      // 默认 1
+	PageSize int  `query:"page_size"` // 默认 20，最多 100
+}
+
+// NotificationList is a page of the inbox, newest first
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int64          `json:"total"`
+	UnreadCount   int64          `json:"unread_count"`
+}
+
+// UnreadCount is the number of unread notifications
+type UnreadCount struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// MarkReadRequest selects the notifications to mark as read
+type MarkReadRequest struct {
+	IDs []uint `json:"ids"` // 为空时标记全部通知
+}
+
+// MarkReadResult is the outcome of marking notifications as read
+type MarkReadResult struct {
+	Updated     int64 `json:"updated"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// NotificationPreferences are the current user's email settings
+type NotificationPreferences struct {
+	UserID          uint      `json:"user_id"`
+	Locale          string    `json:"locale" enum:"zh,en"`
+	Email           string    `json:"email"`            // 为空时使用 OAuth 账户的邮箱
+	ClaimReceipt    bool      `json:"claim_receipt"`    // 领取成功后发送兑换码副本
+	BenefitDepleted bool      `json:"benefit_depleted"` // 福利领完时提醒创建者
+	BenefitExpiring bool      `json:"benefit_expiring"` // 福利即将过期时提醒创建者
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// UpdatePreferencesRequest changes the fields it sets and leaves the others alone
+type UpdatePreferencesRequest struct {
+	Locale          *string `json:"locale" enum:"zh,en"`
+	Email           *string `json:"email"` // 空字符串表示使用 OAuth 账户的邮箱
+	ClaimReceipt    *bool   `json:"claim_receipt"`
+	BenefitDepleted *bool   `json:"benefit_depleted"`
+	BenefitExpiring *bool   `json:"benefit_expiring"`
+}
+
+// UnsubscribeQuery identifies the recipient of an email and what to turn off
+type UnsubscribeQuery struct {
+	Token string `query:"token" binding:"required"`                                    // 邮件中退订链接的令牌
+	Kind  string `query:"kind" enum:"claim_receipt,benefit_depleted,benefit_expiring"` // 为空时退订所有邮件
+}
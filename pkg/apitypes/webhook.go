@@ -0,0 +1,62 @@
+package apitypes
+
+import "time"
+
+// WebhookEndpoint is a webhook endpoint of the current user
+type WebhookEndpoint struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // 为空表示订阅所有事件
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"` // 签名密钥，仅在创建时返回
+}
+
+// WebhookEndpointRequest creates or replaces a webhook endpoint
+type WebhookEndpointRequest struct {
+	URL     string   `json:"url" binding:"required"` // 必须解析到公网地址
+	Secret  string   `json:"secret"`                 // 为空时自动生成
+	Events  []string `json:"events"`                 // 为空表示订阅所有事件
+	Enabled *bool    `json:"enabled"`                // 默认启用
+}
+
+// WebhookEndpointList lists the webhook endpoints of the current user
+type WebhookEndpointList struct {
+	Webhooks []WebhookEndpoint `json:"webhooks"`
+}
+
+// WebhookEndpointResult wraps a created or updated webhook endpoint
+type WebhookEndpointResult struct {
+	Webhook WebhookEndpoint `json:"webhook"`
+}
+
+// WebhookDeliveryQuery limits the delivery log
+type WebhookDeliveryQuery struct {
+	Limit int `query:"limit"` // 默认 50，最多 200
+}
+
+// WebhookDelivery is a delivery of an event to a webhook endpoint
+type WebhookDelivery struct {
+	ID             uint       `json:"id"`
+	UUID           string     `json:"uuid"` // <事件 ID>:<endpoint ID>，即 X-GiftRedeem-Delivery 请求头
+	EndpointID     uint       `json:"endpoint_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"` // 发送的 JSON 请求体
+	Status         string     `json:"status" enum:"pending,succeeded,failed"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error"`
+	ResponseStatus int        `json:"response_status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// WebhookDeliveryList is the most recent deliveries of a webhook endpoint, newest first
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDeliveryResult wraps a queued delivery
+type WebhookDeliveryResult struct {
+	Delivery WebhookDelivery `json:"delivery"`
+}
@@ -0,0 +1,179 @@
+// Package client is a Go client for the GiftRedeem HTTP API. Its methods are
+// generated from the same operation table as the OpenAPI document served at
+// /api/openapi.json and take and return the types of pkg/apitypes.
+//
+//	c := client.New("https://redeem.example.com", client.WithToken(token))
+//	benefits, err := c.GetMyBenefits(ctx)
+//
+// Errors reported by the API are returned as *Error carrying the code of the response.
+package client
+
+//go:generate go run ../../cmd/apigen -o operations_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeout bounds requests made with the default HTTP client
+const defaultTimeout = 30 * time.Second
+
+// Client calls the API of a GiftRedeem server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of a client with a 30 second timeout
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken authenticates requests with a token returned by a login
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New returns a client for the server at baseURL, e.g. https://redeem.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is an error reported by the API
+type Error struct {
+	StatusCode int             // HTTP 状态码，除限流（429）外通常为 200
+	Code       int             // 响应中的错误码
+	Msg        string          // 错误说明
+	Data       json.RawMessage // 部分错误附带的详情，如格式错误的兑换码
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("giftredeem: %s (code %d)", e.Msg, e.Code)
+}
+
+// DecodeData unmarshals the details of the error into v
+func (e *Error) DecodeData(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// ErrorCode returns the code of an error reported by the API, or 0 for other errors
+func ErrorCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
+
+// envelope is the body of every API response
+type envelope struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// do sends a request and decodes the data of the response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("giftredeem: failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("giftredeem: failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("giftredeem: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var result envelope
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("giftredeem: %s %s: unexpected response with status %d: %w", method, path, resp.StatusCode, err)
+	}
+	if result.Code != 0 {
+		return &Error{StatusCode: resp.StatusCode, Code: result.Code, Msg: result.Msg, Data: result.Data}
+	}
+
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("giftredeem: %s %s: failed to decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// encodeQuery encodes the non-zero fields of a struct with query tags
+func encodeQuery(v interface{}) url.Values {
+	values := url.Values{}
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := rt.Field(i).Tag.Get("query")
+		field := rv.Field(i)
+		if name == "" || field.IsZero() {
+			continue
+		}
+		if field.Kind() == reflect.Pointer {
+			field = field.Elem()
+		}
+
+		switch value := field.Interface().(type) {
+		case time.Time:
+			values.Set(name, value.Format(time.RFC3339))
+		case string:
+			values.Set(name, value)
+		case bool:
+			values.Set(name, strconv.FormatBool(value))
+		default:
+			values.Set(name, fmt.Sprint(value))
+		}
+	}
+	return values
+}
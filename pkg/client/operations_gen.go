@@ -0,0 +1,328 @@
+// Code generated by apigen; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"giftredeem/pkg/apitypes"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetProviders lists the enabled OAuth providers
+func (c *Client) GetProviders(ctx context.Context) (*apitypes.ProviderList, error) {
+	var out apitypes.ProviderList
+	if err := c.do(ctx, http.MethodGet, "/api/auth/providers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAuthURL returns the URL to send the browser to for logging in with a provider
+func (c *Client) GetAuthURL(ctx context.Context, provider string) (*apitypes.AuthURL, error) {
+	var out apitypes.AuthURL
+	if err := c.do(ctx, http.MethodGet, "/api/auth/login/"+url.PathEscape(provider), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VerifyCode exchanges an OAuth authorization code for a token
+func (c *Client) VerifyCode(ctx context.Context, provider string, body apitypes.VerifyCodeRequest) (*apitypes.LoginResult, error) {
+	var out apitypes.LoginResult
+	if err := c.do(ctx, http.MethodPost, "/api/auth/verify/"+url.PathEscape(provider), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProfile returns the current user and their OAuth accounts
+//
+// The client must have been created WithToken.
+func (c *Client) GetProfile(ctx context.Context) (*apitypes.ProfileResult, error) {
+	var out apitypes.ProfileResult
+	if err := c.do(ctx, http.MethodGet, "/api/auth/profile", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateBenefit creates a benefit with its redemption codes
+//
+// The client must have been created WithToken.
+func (c *Client) CreateBenefit(ctx context.Context, body apitypes.CreateBenefitRequest) (*apitypes.CreateBenefitResult, error) {
+	var out apitypes.CreateBenefitResult
+	if err := c.do(ctx, http.MethodPost, "/api/benefits", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMyBenefits lists the benefits created by the current user
+//
+// The client must have been created WithToken.
+func (c *Client) GetMyBenefits(ctx context.Context) (*apitypes.BenefitList, error) {
+	var out apitypes.BenefitList
+	if err := c.do(ctx, http.MethodGet, "/api/benefits/my", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateBenefitStatus pauses, resumes or deletes a benefit
+//
+// The client must have been created WithToken.
+func (c *Client) UpdateBenefitStatus(ctx context.Context, uuid string, body apitypes.UpdateBenefitStatusRequest) error {
+	return c.do(ctx, http.MethodPut, "/api/benefits/"+url.PathEscape(uuid)+"/status", nil, body, nil)
+}
+
+// GetBenefitClaims lists the claims on a benefit with its tier distribution and feedback summary
+//
+// The client must have been created WithToken.
+func (c *Client) GetBenefitClaims(ctx context.Context, uuid string) (*apitypes.BenefitClaims, error) {
+	var out apitypes.BenefitClaims
+	if err := c.do(ctx, http.MethodGet, "/api/benefits/"+url.PathEscape(uuid)+"/claims", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplaceCode issues a replacement for a code reported as already used
+//
+// The client must have been created WithToken.
+func (c *Client) ReplaceCode(ctx context.Context, uuid string, id uint, body apitypes.ReplaceCodeRequest) (*apitypes.ClaimItemResult, error) {
+	var out apitypes.ClaimItemResult
+	if err := c.do(ctx, http.MethodPost, "/api/benefits/"+url.PathEscape(uuid)+"/claims/"+strconv.FormatUint(uint64(id), 10)+"/replace", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeClaim revokes a claim, returning or burning its codes
+//
+// The client must have been created WithToken.
+func (c *Client) RevokeClaim(ctx context.Context, uuid string, id uint, body apitypes.RevokeClaimRequest) (*apitypes.RevokeClaimResult, error) {
+	var out apitypes.RevokeClaimResult
+	if err := c.do(ctx, http.MethodDelete, "/api/benefits/"+url.PathEscape(uuid)+"/claims/"+strconv.FormatUint(uint64(id), 10), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReviewClaim approves or rejects a flagged or held claim
+//
+// The client must have been created WithToken.
+func (c *Client) ReviewClaim(ctx context.Context, uuid string, id uint, body apitypes.ReviewClaimRequest) (*apitypes.ReviewClaimResult, error) {
+	var out apitypes.ReviewClaimResult
+	if err := c.do(ctx, http.MethodPost, "/api/benefits/"+url.PathEscape(uuid)+"/claims/"+strconv.FormatUint(uint64(id), 10)+"/review", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBenefitAudit lists the audit events of a benefit
+//
+// The client must have been created WithToken.
+func (c *Client) GetBenefitAudit(ctx context.Context, uuid string, query apitypes.AuditQuery) (*apitypes.AuditPage, error) {
+	var out apitypes.AuditPage
+	if err := c.do(ctx, http.MethodGet, "/api/benefits/"+url.PathEscape(uuid)+"/audit", encodeQuery(query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMyClaims lists the claims of the current user
+//
+// The client must have been created WithToken.
+func (c *Client) GetMyClaims(ctx context.Context) (*apitypes.ClaimList, error) {
+	var out apitypes.ClaimList
+	if err := c.do(ctx, http.MethodGet, "/api/claims/my", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SubmitFeedback reports whether a claimed code worked
+//
+// The client must have been created WithToken.
+func (c *Client) SubmitFeedback(ctx context.Context, id uint, body apitypes.FeedbackRequest) (*apitypes.FeedbackResult, error) {
+	var out apitypes.FeedbackResult
+	if err := c.do(ctx, http.MethodPost, "/api/claims/"+strconv.FormatUint(uint64(id), 10)+"/feedback", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBenefit returns a benefit and whether the current user can claim it
+func (c *Client) GetBenefit(ctx context.Context, uuid string) (*apitypes.BenefitInfo, error) {
+	var out apitypes.BenefitInfo
+	if err := c.do(ctx, http.MethodGet, "/api/claim/"+url.PathEscape(uuid), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetChallenge issues the challenge to solve before claiming a benefit
+func (c *Client) GetChallenge(ctx context.Context, uuid string) (*apitypes.Challenge, error) {
+	var out apitypes.Challenge
+	if err := c.do(ctx, http.MethodGet, "/api/claim/"+url.PathEscape(uuid)+"/challenge", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClaimBenefit claims a benefit
+//
+// The client must have been created WithToken.
+func (c *Client) ClaimBenefit(ctx context.Context, uuid string, body apitypes.ClaimRequest) (*apitypes.ClaimResult, error) {
+	var out apitypes.ClaimResult
+	if err := c.do(ctx, http.MethodPost, "/api/claim/"+url.PathEscape(uuid), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWebhooks lists the webhook endpoints of the current user
+//
+// The client must have been created WithToken.
+func (c *Client) GetWebhooks(ctx context.Context) (*apitypes.WebhookEndpointList, error) {
+	var out apitypes.WebhookEndpointList
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWebhook registers a webhook endpoint
+//
+// The client must have been created WithToken.
+func (c *Client) CreateWebhook(ctx context.Context, body apitypes.WebhookEndpointRequest) (*apitypes.WebhookEndpointResult, error) {
+	var out apitypes.WebhookEndpointResult
+	if err := c.do(ctx, http.MethodPost, "/api/webhooks", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWebhook changes a webhook endpoint
+//
+// The client must have been created WithToken.
+func (c *Client) UpdateWebhook(ctx context.Context, id uint, body apitypes.WebhookEndpointRequest) (*apitypes.WebhookEndpointResult, error) {
+	var out apitypes.WebhookEndpointResult
+	if err := c.do(ctx, http.MethodPut, "/api/webhooks/"+strconv.FormatUint(uint64(id), 10), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhook removes a webhook endpoint
+//
+// The client must have been created WithToken.
+func (c *Client) DeleteWebhook(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, "/api/webhooks/"+strconv.FormatUint(uint64(id), 10), nil, nil, nil)
+}
+
+// GetWebhookDeliveries lists the most recent deliveries of a webhook endpoint
+//
+// The client must have been created WithToken.
+func (c *Client) GetWebhookDeliveries(ctx context.Context, id uint, query apitypes.WebhookDeliveryQuery) (*apitypes.WebhookDeliveryList, error) {
+	var out apitypes.WebhookDeliveryList
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks/"+strconv.FormatUint(uint64(id), 10)+"/deliveries", encodeQuery(query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TestWebhook queues a ping event for a webhook endpoint
+//
+// The client must have been created WithToken.
+func (c *Client) TestWebhook(ctx context.Context, id uint) (*apitypes.WebhookDeliveryResult, error) {
+	var out apitypes.WebhookDeliveryResult
+	if err := c.do(ctx, http.MethodPost, "/api/webhooks/"+strconv.FormatUint(uint64(id), 10)+"/test", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNotifications lists the in-app notifications of the current user
+//
+// The client must have been created WithToken.
+func (c *Client) GetNotifications(ctx context.Context, query apitypes.NotificationQuery) (*apitypes.NotificationList, error) {
+	var out apitypes.NotificationList
+	if err := c.do(ctx, http.MethodGet, "/api/notifications", encodeQuery(query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUnreadCount returns the number of unread notifications
+//
+// The client must have been created WithToken.
+func (c *Client) GetUnreadCount(ctx context.Context) (*apitypes.UnreadCount, error) {
+	var out apitypes.UnreadCount
+	if err := c.do(ctx, http.MethodGet, "/api/notifications/unread-count", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkNotificationsRead marks notifications as read
+//
+// The client must have been created WithToken.
+func (c *Client) MarkNotificationsRead(ctx context.Context, body apitypes.MarkReadRequest) (*apitypes.MarkReadResult, error) {
+	var out apitypes.MarkReadResult
+	if err := c.do(ctx, http.MethodPost, "/api/notifications/read", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNotificationPreferences returns the email settings of the current user
+//
+// The client must have been created WithToken.
+func (c *Client) GetNotificationPreferences(ctx context.Context) (*apitypes.NotificationPreferences, error) {
+	var out apitypes.NotificationPreferences
+	if err := c.do(ctx, http.MethodGet, "/api/notifications/preferences", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateNotificationPreferences changes the email settings of the current user
+//
+// The client must have been created WithToken.
+func (c *Client) UpdateNotificationPreferences(ctx context.Context, body apitypes.UpdatePreferencesRequest) (*apitypes.NotificationPreferences, error) {
+	var out apitypes.NotificationPreferences
+	if err := c.do(ctx, http.MethodPut, "/api/notifications/preferences", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Unsubscribe turns off emails for the recipient of an unsubscribe link
+func (c *Client) Unsubscribe(ctx context.Context, query apitypes.UnsubscribeQuery) error {
+	return c.do(ctx, http.MethodPost, "/api/notifications/unsubscribe", encodeQuery(query), nil, nil)
+}
+
+// GetAuditEvents queries the global audit log
+//
+// The client must have been created WithToken.
+func (c *Client) GetAuditEvents(ctx context.Context, query apitypes.AdminAuditQuery) (*apitypes.AuditPage, error) {
+	var out apitypes.AuditPage
+	if err := c.do(ctx, http.MethodGet, "/api/admin/audit", encodeQuery(query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateUserStatus bans, restores or deletes a user account
+//
+// The client must have been created WithToken.
+func (c *Client) UpdateUserStatus(ctx context.Context, id uint, body apitypes.UpdateUserStatusRequest) (*apitypes.UserStatusResult, error) {
+	var out apitypes.UserStatusResult
+	if err := c.do(ctx, http.MethodPut, "/api/admin/users/"+strconv.FormatUint(uint64(id), 10)+"/status", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
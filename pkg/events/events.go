@@ -0,0 +1,171 @@
+// Package events defines the domain events emitted by GiftRedeem and an in-process
+// bus to subscribe to them. Events are written to a transactional outbox together with
+// the change that caused them and handed to subscribers at least once, so handlers
+// must be idempotent; Event.ID is stable across redeliveries and can be used to dedupe.
+//
+// Register handlers on DefaultBus, or on a bus passed to server.Run with
+// server.WithBus, before starting the server with giftredeem/pkg/server; the outbox
+// dispatcher started by Run then delivers every event to them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	BenefitCreated       = "benefit.created"
+	BenefitStatusChanged = "benefit.status_changed"
+	BenefitDepleted      = "benefit.depleted"
+	BenefitExpiring      = "benefit.expiring"
+	ClaimCreated         = "claim.created"
+	ClaimApproved        = "claim.approved"
+	ClaimCodeReplaced    = "claim.code_replaced"
+	UserStatusChanged    = "user.status_changed"
+)
+
+// Event is a domain event read from the outbox
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Decode unmarshals the event payload into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// BenefitPayload describes a benefit in event payloads
+type BenefitPayload struct {
+	ID           uint      `json:"id"`
+	UUID         string    `json:"uuid"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	CreatorID    uint      `json:"creator_id"`
+	TotalCount   int       `json:"total_count"`
+	ClaimedCount int       `json:"claimed_count"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// ClaimPayload describes a claim in event payloads
+type ClaimPayload struct {
+	ID            uint      `json:"id"`
+	UserID        uint      `json:"user_id"`
+	OAuthProvider string    `json:"oauth_provider"`
+	ClaimedAt     time.Time `json:"claimed_at"`
+	CodeCount     int       `json:"code_count"`
+	ReviewStatus  string    `json:"review_status,omitempty"` // flagged/pending/approved
+}
+
+// ReplacementPayload describes a code replaced after the claimer reported it as already used
+type ReplacementPayload struct {
+	ItemID            uint   `json:"item_id"`             // 被替换的领取条目
+	ReplacementItemID uint   `json:"replacement_item_id"` // 新发放的领取条目
+	Tier              string `json:"tier,omitempty"`
+}
+
+// BenefitEvent is the payload of every benefit.* and claim.* event
+type BenefitEvent struct {
+	Benefit        BenefitPayload      `json:"benefit"`
+	PreviousStatus string              `json:"previous_status,omitempty"` // benefit.status_changed
+	Claim          *ClaimPayload       `json:"claim,omitempty"`           // claim.created, claim.approved, claim.code_replaced
+	Replacement    *ReplacementPayload `json:"replacement,omitempty"`     // claim.code_replaced
+}
+
+// UserEvent is the payload of user.* events
+type UserEvent struct {
+	UserID         uint   `json:"user_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// Handler processes an event. Returning an error makes the dispatcher retry the
+// event for this subscriber later; other subscribers are not affected.
+type Handler func(ctx context.Context, e Event) error
+
+// Subscriber is a named handler registered on a bus
+type Subscriber struct {
+	Name    string
+	Types   []string // 为空表示订阅所有事件
+	Handler Handler
+}
+
+// Wants reports whether the subscriber is interested in the event type
+func (s *Subscriber) Wants(eventType string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrDuplicateSubscriber is returned when a subscriber name is already registered
+var ErrDuplicateSubscriber = errors.New("subscriber already registered")
+
+// Bus keeps track of subscribers. The name of a subscriber identifies it in the
+// outbox bookkeeping, so it must stay the same across restarts.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string]*Subscriber
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string]*Subscriber)}
+}
+
+// Subscribe registers handler under name for the given event types (all types if none are given)
+func (b *Bus) Subscribe(name string, handler Handler, types ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.subscribers[name]; exists {
+		return ErrDuplicateSubscriber
+	}
+
+	b.subscribers[name] = &Subscriber{Name: name, Types: types, Handler: handler}
+	return nil
+}
+
+// Unsubscribe removes a subscriber
+func (b *Bus) Unsubscribe(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, name)
+}
+
+// Subscribers returns the subscribers interested in eventType, ordered by name
+func (b *Bus) Subscribers(eventType string) []*Subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := []*Subscriber{}
+	for _, s := range b.subscribers {
+		if s.Wants(eventType) {
+			result = append(result, s)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// DefaultBus is the bus used by server.Run unless another one is given
+var DefaultBus = NewBus()
+
+// Subscribe registers a handler on DefaultBus
+func Subscribe(name string, handler Handler, types ...string) error {
+	return DefaultBus.Subscribe(name, handler, types...)
+}
@@ -0,0 +1,288 @@
+// Package server runs the GiftRedeem server: the HTTP API, the frontend, the outbox
+// dispatcher and the background workers. cmd/server is a thin wrapper around it;
+// teams embedding the service call it from their own main package after registering
+// their event handlers, which then receive every domain event at least once.
+//
+//	bus := events.NewBus()
+//	bus.Subscribe("crm-sync", syncClaim, events.ClaimCreated)
+//
+//	cfg, err := server.LoadConfig("config.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	if err := server.Run(ctx, cfg, server.WithBus(bus)); err != nil {
+//		log.Fatal(err)
+//	}
+package server
+
+import (
+	"context"
+	"fmt"
+	"giftredeem/internal/api"
+	"giftredeem/internal/auth"
+	"giftredeem/internal/benefit"
+	"giftredeem/internal/challenge"
+	"giftredeem/internal/config"
+	"giftredeem/internal/db"
+	"giftredeem/internal/frontend"
+	"giftredeem/internal/live"
+	"giftredeem/internal/logging"
+	"giftredeem/internal/metrics"
+	"giftredeem/internal/notify"
+	"giftredeem/internal/outbox"
+	"giftredeem/internal/secretbox"
+	"giftredeem/internal/site"
+	"giftredeem/internal/tracing"
+	"giftredeem/internal/webhook"
+	"giftredeem/pkg/events"
+	"giftredeem/vueweb/redeem"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config is the complete server configuration, see LoadConfig
+type Config = config.Config
+
+// LoadConfig builds the configuration from the defaults, the YAML or TOML file at path
+// (skipped when empty) and the environment, then validates it. The configuration is
+// returned even when validation fails so that it can still be printed.
+func LoadConfig(path string) (*Config, error) {
+	return config.Load(path)
+}
+
+// Option configures Run
+type Option func(*options)
+
+type options struct {
+	bus       *events.Bus
+	logOutput io.Writer
+}
+
+// WithBus dispatches domain events to bus instead of events.DefaultBus. Handlers
+// must be registered on it before Run is called.
+func WithBus(bus *events.Bus) Option {
+	return func(o *options) {
+		o.bus = bus
+	}
+}
+
+// WithLogOutput writes logs to w instead of standard error
+func WithLogOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.logOutput = w
+	}
+}
+
+// Run starts the server and blocks until ctx is cancelled, then stops accepting
+// requests, waits up to cfg.Server.ShutdownTimeout for in-flight ones and stops the
+// background workers. The outbox dispatcher delivers events to the subscribers of
+// the bus, alongside the built-in webhook and notification subscribers.
+//
+// The server keeps its state in package variables, so Run may only be called once
+// per process.
+func Run(ctx context.Context, cfg *Config, opts ...Option) error {
+	o := options{bus: events.DefaultBus, logOutput: os.Stderr}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := logging.Setup(cfg.Logging.Logging(), o.logOutput); err != nil {
+		return err
+	}
+	if cfg.IsDev() && cfg.Auth.JWTSecret == auth.DevJWTSecret {
+		slog.Warn("Using the built-in JWT secret, do not run dev mode in production")
+	}
+	if cfg.IsDev() && cfg.SMTP.Host != "" && cfg.Site.PublicURL == "" {
+		slog.Warn("PUBLIC_URL is not set, links in emails point at the default frontend address")
+	}
+
+	// Export spans to an OTLP collector when tracing is enabled
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Tracing())
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		// 导出尚未发送的 span；关闭服务可能已用完关闭超时
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
+	auth.SetJWTSecret(cfg.Auth.JWTSecret)
+	challenge.SetSecret(cfg.ChallengeSecret())
+	secretbox.SetKey(cfg.EncryptionKey())
+
+	// Initialize database connection
+	if err := db.Initialize(cfg.Database.DB()); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Warn("Failed to close database", "error", err)
+		}
+	}()
+	if sqlDB, err := db.DB.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, cfg.Database.Name); err != nil {
+			return fmt.Errorf("failed to register database metrics: %w", err)
+		}
+	}
+
+	// Make o_auth_providers match the configured OAuth providers
+	if _, err := auth.SyncProviders(ctx, cfg.OAuth.ProviderConfigs()); err != nil {
+		return fmt.Errorf("failed to sync OAuth providers: %w", err)
+	}
+
+	// Public addresses used for links, OAuth redirect URIs and emails
+	if err := site.Configure(cfg.Site.Site()); err != nil {
+		return fmt.Errorf("invalid site configuration: %w", err)
+	}
+
+	// Webhooks may only be delivered to public addresses unless loopback is allowed
+	webhook.Configure(cfg.Webhook.Webhooks())
+
+	// CAPTCHA challenges are available when a CAPTCHA secret is configured
+	if err := challenge.ConfigureCaptcha(cfg.Challenge.Captcha()); err != nil {
+		return fmt.Errorf("invalid CAPTCHA configuration: %w", err)
+	}
+
+	// Register the built-in subscribers next to those of the caller, before the outbox
+	// dispatcher below starts
+	if err := registerSubscribers(o.bus, cfg); err != nil {
+		return err
+	}
+
+	// Start the outbox dispatcher and the background workers. They run until the
+	// HTTP server has drained, so events published by in-flight requests are still
+	// dispatched.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+	for _, run := range []func(context.Context){
+		outbox.NewDispatcher(o.bus).Run,
+		webhook.NewWorker().Run,
+		benefit.NewExpiryWatcher(cfg.Benefits.ExpiringWindow.Duration).Run,
+		live.DefaultHub.Run,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Set up the API router
+	router, err := api.SetupRouter(api.RouterConfig{
+		Proxy:             cfg.Proxy.ClientIP(),
+		RateLimitStore:    cfg.RateLimit.Store,
+		OAuthIPLimit:      cfg.RateLimit.OAuthIP,
+		ClaimIPLimit:      cfg.RateLimit.ClaimIP,
+		ClaimUserLimit:    cfg.RateLimit.ClaimUser,
+		ClaimBenefitLimit: cfg.RateLimit.ClaimBenefit,
+		StreamsPerIP:      cfg.RateLimit.StreamsPerIP,
+		RequiredProviders: cfg.OAuth.RequiredProviders(),
+		MetricsToken:      cfg.Metrics.Token,
+		TracingService:    tracingService(cfg.Tracing),
+		Frontend:          loadFrontend(cfg.Server.FrontendDir),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up router: %w", err)
+	}
+
+	// Start the server
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// 关闭时通知 SSE 连接断开，否则长连接会一直阻塞关闭
+	server.RegisterOnShutdown(live.DefaultHub.Shutdown)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server starting", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting requests and wait for in-flight ones, such as claim
+	// transactions, to finish
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.Server.ShutdownTimeout.Duration)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Server did not shut down cleanly", "error", err)
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
+
+// registerSubscribers registers the webhook, inbox and, when SMTP is configured,
+// email subscribers on bus
+func registerSubscribers(bus *events.Bus, cfg *Config) error {
+	if err := webhook.Register(bus); err != nil {
+		return fmt.Errorf("failed to register webhook subscriber: %w", err)
+	}
+	if err := notify.RegisterInbox(bus); err != nil {
+		return fmt.Errorf("failed to register inbox subscriber: %w", err)
+	}
+
+	// Email notifications are enabled when an SMTP host is configured
+	if smtpConfig := cfg.SMTP.Mailer(); smtpConfig.Enabled() {
+		notifier := notify.NewNotifier(notify.NewSMTPMailer(smtpConfig), site.Current().Frontend(), site.Current().BaseURL(nil))
+		if err := notifier.Register(bus); err != nil {
+			return fmt.Errorf("failed to register email subscriber: %w", err)
+		}
+		slog.Info("Email notifications enabled", "host", smtpConfig.Host, "port", smtpConfig.Port)
+	}
+	return nil
+}
+
+// tracingService returns the service name of request spans, or "" when tracing is off
+func tracingService(cfg config.TracingConfig) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	return cfg.ServiceName
+}
+
+// loadFrontend returns the frontend to serve: the configured directory, else the build
+// embedded in the binary, else frontend.DefaultDir. Without a build only the API is
+// served.
+func loadFrontend(dir string) *frontend.Server {
+	files, embedded := redeem.Dist()
+	source := "embedded"
+	if dir != "" || !embedded {
+		if dir == "" {
+			dir = frontend.DefaultDir
+		}
+		files, source = os.DirFS(dir), dir
+	}
+
+	server, err := frontend.New(files)
+	if err != nil {
+		slog.Warn("Frontend not served", "source", source, "error", err)
+		return nil
+	}
+	slog.Info("Serving frontend", "source", source)
+	return server
+}
@@ -0,0 +1,22 @@
+//go:build embedfrontend
+
+package redeem
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// 需要先执行 npm run build 生成 dist
+//
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the built frontend embedded in the binary
+func Dist() (fs.FS, bool) {
+	files, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	return files, true
+}
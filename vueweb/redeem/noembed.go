@@ -0,0 +1,15 @@
+//go:build !embedfrontend
+
+// Package redeem embeds the built Vue frontend into the server binary when it is
+// built with the embedfrontend tag, after running npm run build:
+//
+//	go build -tags embedfrontend ./cmd/server
+package redeem
+
+import "io/fs"
+
+// Dist returns the built frontend embedded in the binary. Without the embedfrontend
+// build tag nothing is embedded.
+func Dist() (fs.FS, bool) {
+	return nil, false
+}
@@ -0,0 +1,32 @@
+// 为构建产物生成 .br 和 .gz 预压缩文件，由服务端按 Accept-Encoding 直接返回
+import { readdirSync, readFileSync, statSync, writeFileSync } from 'node:fs'
+import { join } from 'node:path'
+import { brotliCompressSync, gzipSync, constants } from 'node:zlib'
+
+const root = process.argv[2] || 'dist'
+const compressible = /\.(html|js|mjs|css|json|svg|txt|xml|map|ico|wasm)$/
+const minSize = 1024
+
+function walk(dir) {
+  for (const name of readdirSync(dir)) {
+    const file = join(dir, name)
+    if (statSync(file).isDirectory()) {
+      walk(file)
+      continue
+    }
+    if (!compressible.test(name)) continue
+
+    const data = readFileSync(file)
+    if (data.length < minSize) continue
+
+    const br = brotliCompressSync(data, {
+      params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY },
+    })
+    const gz = gzipSync(data, { level: 9 })
+    // 压缩后没有变小的文件不必保留压缩版本
+    if (br.length < data.length) writeFileSync(file + '.br', br)
+    if (gz.length < data.length) writeFileSync(file + '.gz', gz)
+  }
+}
+
+walk(root)